@@ -11,11 +11,15 @@ import (
 	"flag"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
 	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
 	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/driver"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/metrics"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/logging"
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/container-object-storage-interface-provisioner-sidecar/pkg/provisioner"
@@ -45,7 +49,19 @@ func main() {
 const driverName = config.DriverName
 
 var (
-	driverAddress = flag.String("driver-address", "", "driver address for socket")
+	driverAddress    = flag.String("driver-address", "", "driver address for socket")
+	metricsAddress   = flag.String("metrics-address", ":8080", "address to expose Prometheus metrics on, empty to disable")
+	auditLog         = flag.String("audit-log", "", "file to append the JSON lines audit log of IAM and policy mutations to, \"-\" for stdout")
+	auditWebhook     = flag.String("audit-webhook", "", "URL to POST audit events to as JSON")
+	clusterID        = flag.String("cluster-id", "", "identifier of the cluster, tagged on the buckets and IAM users the driver creates")
+	orphanGC         = flag.String("orphan-gc", driver.GCModeOff, "mode of the garbage collector for IAM users whose BucketAccess is gone: off, dry-run or enforce (requires --cluster-id)")
+	orphanGCInterval = flag.Duration("orphan-gc-interval", time.Hour, "how often the orphan garbage collector runs")
+	policyDrift      = flag.Duration("policy-drift-interval", 5*time.Minute, "how often bucket policies are checked for removed or altered statements and repaired, 0 to disable")
+	dryRun           = flag.Bool("dry-run", false, "log the bucket, policy and IAM mutations the driver would perform and answer them with synthetic responses instead of changing storage")
+	pdpURL           = flag.String("pdp-url", "", "URL of a policy decision point (e.g. OPA at http://localhost:8181/v1/data/cosi/grant) consulted before access is granted, empty to disable")
+	pdpFailOpen      = flag.Bool("pdp-fail-open", false, "grant access when the policy decision point cannot be reached instead of denying it")
+	credentialSrcs   = flag.String("account-credential-sources", "", "comma-separated path and URL prefixes that the file and http account credential sources may read, empty to only read account credentials from Secrets")
+	tenantConfig     = flag.String("tenant-config", "", "namespace/name of the ConfigMap mapping namespaces to the accounts they may use, empty to disable tenant isolation")
 )
 
 func init() {
@@ -54,6 +70,7 @@ func init() {
 		klog.Exitf("failed to set logtostderr flag: %v", err)
 	}
 	flag.Parse()
+	logging.Install()
 
 	// Default `driverAddress` based on environment
 	if *driverAddress == "" {
@@ -71,7 +88,22 @@ func init() {
 
 func run(ctx context.Context) error {
 	klog.Info("Driver name: ", driverName)
-	identityServer, bucketProvisioner, err := driver.NewDriver(ctx, driverName)
+
+	if err := setupAudit(); err != nil {
+		return err
+	}
+
+	identityServer, bucketProvisioner, err := driver.NewDriver(ctx, driverName, driver.Options{
+		TenantConfig:           *tenantConfig,
+		ClusterID:              *clusterID,
+		OrphanGC:               *orphanGC,
+		OrphanGCInterval:       *orphanGCInterval,
+		PolicyDriftInterval:    *policyDrift,
+		DryRun:                 *dryRun,
+		PolicyDecisionURL:      *pdpURL,
+		PolicyDecisionFailOpen: *pdpFailOpen,
+		CredentialSources:      splitList(*credentialSrcs),
+	})
 	if err != nil {
 		return err
 	}
@@ -83,6 +115,46 @@ func run(ctx context.Context) error {
 		return err
 	}
 
+	if *metricsAddress != "" {
+		go func() {
+			if err := metrics.Serve(ctx, *metricsAddress); err != nil {
+				klog.ErrorS(err, "Metrics server failed", "address", *metricsAddress)
+			}
+		}()
+	}
+
 	klog.Info("Starting COSI provisioner server")
 	return server.Run(ctx)
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func setupAudit() error {
+	var sinks []audit.Sink
+	if *auditLog != "" {
+		sink, err := audit.NewFileSink(*auditLog)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if *auditWebhook != "" {
+		sinks = append(sinks, audit.NewWebhookSink(*auditWebhook, 10*time.Second))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	klog.InfoS("Audit logging enabled", "file", *auditLog, "webhook", *auditWebhook)
+	audit.SetDefault(audit.NewAuditor(driverName, sinks...))
+	return nil
+}
@@ -0,0 +1,40 @@
+# Policy decision point for the s3-iam COSI driver, served by OPA:
+#   opa run --server --addr :8181 grant.rego
+# and started with --pdp-url=http://<opa>:8181/v1/data/cosi/grant
+package cosi.grant
+
+import rego.v1
+
+default allow := false
+
+# Only namespaces labelled for object storage by naming convention may get access
+team_namespace if startswith(input.bucketAccess.metadata.namespace, "team-")
+
+# Production buckets are read-only for everyone but their owners
+restricted if {
+	startswith(input.bucketName, "prod-")
+	input.bucketAccess.metadata.namespace != input.bucket.spec.bucketClaim.namespace
+}
+
+allow if {
+	team_namespace
+	not restricted
+}
+
+# The driver denies decisions that widen the requested actions, so restricted accesses get the
+# read-only actions their access mode covers, and are denied if it covers none
+allow if {
+	team_namespace
+	restricted
+	count(actions) > 0
+}
+
+reason := sprintf("namespace %s may not access buckets", [input.bucketAccess.metadata.namespace]) if not allow
+
+read_only := ["s3:GetObject", "s3:ListBucket", "s3:GetBucketLocation"]
+
+actions := [action | some action in read_only; covered(action)] if restricted
+
+covered(action) if action in input.actions
+
+covered(_) if "s3:*" in input.actions
@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+// Command stub is a policy decision point for local testing of --pdp-url. It denies BucketAccesses
+// in namespaces listed in -deny, narrows the actions of namespaces listed in -read-only to the
+// read-only actions they requested, and allows everything else as requested.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/pdp"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+var (
+	address  = flag.String("address", ":8181", "address to listen on")
+	deny     = flag.String("deny", "", "comma separated namespaces to deny")
+	readOnly = flag.String("read-only", "", "comma separated namespaces to restrict to read-only actions")
+)
+
+func main() {
+	flag.Parse()
+	denied := split(*deny)
+	restricted := split(*readOnly)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Input pdp.Input `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Input.BucketAccess == nil {
+			http.Error(w, "expected {\"input\": ...}", http.StatusBadRequest)
+			return
+		}
+
+		namespace := request.Input.BucketAccess.Namespace
+		decision := pdp.Decision{Allow: true}
+		switch {
+		case denied[namespace]:
+			decision = pdp.Decision{Allow: false, Reason: "namespace " + namespace + " may not access buckets"}
+		case restricted[namespace]:
+			// The driver denies decisions that widen the requested actions
+			decision.Actions = readOnlyActions(request.Input.Actions)
+			if len(decision.Actions) == 0 {
+				decision = pdp.Decision{Allow: false, Reason: "namespace " + namespace + " is restricted to read-only access"}
+			}
+		}
+		log.Printf("%s/%s on %s: %+v", namespace, request.Input.BucketAccess.Name, request.Input.BucketName, decision)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"result": decision}); err != nil {
+			log.Printf("failed to write decision: %v", err)
+		}
+	})
+
+	server := &http.Server{Addr: *address, ReadHeaderTimeout: 10 * time.Second}
+	log.Printf("policy decision point stub listening on %s", *address)
+	log.Fatal(server.ListenAndServe())
+}
+
+// readOnlyActions returns the read-only actions covered by requested
+func readOnlyActions(requested []string) []string {
+	var actions []string
+	for _, action := range s3client.GetActionStrings(s3client.ReadOnlyActions) {
+		for _, r := range requested {
+			if r == action || r == "s3:*" {
+				actions = append(actions, action)
+				break
+			}
+		}
+	}
+	return actions
+}
+
+func split(list string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,158 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Audited actions
+const (
+	ActionCreateUser         = "CreateUser"
+	ActionCreateAccessKey    = "CreateAccessKey"
+	ActionDeleteUser         = "DeleteUser"
+	ActionPutBucketPolicy    = "PutBucketPolicy"
+	ActionDeleteBucketPolicy = "DeleteBucketPolicy"
+	ActionCreateRole         = "CreateRole"
+	ActionUpdateTrustPolicy  = "UpdateAssumeRolePolicy"
+	ActionPutRolePolicy      = "PutRolePolicy"
+	ActionDeleteRole         = "DeleteRole"
+	ActionSetBucketQuota     = "SetBucketQuota"
+	ActionTagUser            = "TagUser"
+	ActionPutBucketTagging   = "PutBucketTagging"
+)
+
+// Outcomes of an audited action
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Source identifies the Kubernetes object whose reconciliation triggered a mutation
+type Source struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+}
+
+// PolicyDiff lists the bucket policy statements added and removed by a policy mutation
+type PolicyDiff struct {
+	Added   []json.RawMessage `json:"added,omitempty"`
+	Removed []json.RawMessage `json:"removed,omitempty"`
+}
+
+// Event is a single audit record, written as one JSON line
+type Event struct {
+	Time         time.Time       `json:"time"`
+	Actor        string          `json:"actor"`
+	Action       string          `json:"action"`
+	Outcome      string          `json:"outcome"`
+	Error        string          `json:"error,omitempty"`
+	DryRun       bool            `json:"dryRun,omitempty"`
+	Endpoint     string          `json:"endpoint,omitempty"`
+	Bucket       string          `json:"bucket,omitempty"`
+	User         string          `json:"user,omitempty"`
+	Role         string          `json:"role,omitempty"`
+	AccessKeyID  string          `json:"accessKeyId,omitempty"`
+	Source       *Source         `json:"source,omitempty"`
+	PolicyBefore json.RawMessage `json:"policyBefore,omitempty"`
+	PolicyAfter  json.RawMessage `json:"policyAfter,omitempty"`
+	PolicyDiff   *PolicyDiff     `json:"policyDiff,omitempty"`
+}
+
+// Sink receives audit events
+type Sink interface {
+	Write(event *Event) error
+}
+
+// Auditor stamps events and fans them out to its sinks
+type Auditor struct {
+	actor string
+	sinks []Sink
+}
+
+// NewAuditor creates an auditor that attributes events to actor
+func NewAuditor(actor string, sinks ...Sink) *Auditor {
+	return &Auditor{
+		actor: actor,
+		sinks: sinks,
+	}
+}
+
+var (
+	mu             sync.RWMutex
+	defaultAuditor *Auditor
+)
+
+// SetDefault installs the auditor used by Record. A nil auditor disables auditing.
+func SetDefault(a *Auditor) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultAuditor = a
+}
+
+type sourceKey struct{}
+
+// WithSource returns a context that attributes audit events to the given Kubernetes object
+func WithSource(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, &source)
+}
+
+// Record writes event to the default auditor, filling in the time, actor and source
+func Record(ctx context.Context, event Event) {
+	mu.RLock()
+	a := defaultAuditor
+	mu.RUnlock()
+	if a == nil {
+		return
+	}
+
+	event.Time = time.Now().UTC()
+	event.Actor = a.actor
+	if event.Outcome == "" {
+		event.Outcome = OutcomeSuccess
+	}
+	if source, ok := ctx.Value(sourceKey{}).(*Source); ok && event.Source == nil {
+		event.Source = source
+	}
+	event.PolicyBefore = validPolicy(event.PolicyBefore)
+	event.PolicyAfter = validPolicy(event.PolicyAfter)
+
+	for _, sink := range a.sinks {
+		if err := sink.Write(&event); err != nil {
+			klog.ErrorS(err, "failed to write audit event", "action", event.Action)
+		}
+	}
+}
+
+// validPolicy returns policy if it is valid JSON and otherwise the policy quoted as a JSON string,
+// so that a malformed policy is still recorded and cannot make the whole event fail to marshal
+func validPolicy(policy json.RawMessage) json.RawMessage {
+	if len(policy) == 0 || json.Valid(policy) {
+		return policy
+	}
+	quoted, err := json.Marshal(string(policy))
+	if err != nil {
+		return nil
+	}
+	return quoted
+}
+
+// Failure sets the outcome of event from err and returns it for chaining
+func (e Event) Failure(err error) Event {
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		e.Error = err.Error()
+	}
+	return e
+}
@@ -0,0 +1,220 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSink keeps the events written to it
+type recordingSink struct {
+	events []*Event
+}
+
+func (s *recordingSink) Write(event *Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// installAuditor makes an auditor writing to sinks the default for the test
+func installAuditor(t *testing.T, sinks ...Sink) {
+	t.Helper()
+	SetDefault(NewAuditor("s3-iam-cosi-driver", sinks...))
+	t.Cleanup(func() { SetDefault(nil) })
+}
+
+func TestRecord(t *testing.T) {
+	sink := &recordingSink{}
+	installAuditor(t, sink)
+	ctx := WithSource(context.Background(), Source{Kind: "BucketAccess", Namespace: "team-a", Name: "ba1"})
+
+	Record(ctx, Event{Action: ActionCreateUser, User: "cosi-user-ba-1"})
+	Record(ctx, Event{Action: ActionDeleteUser, User: "cosi-user-ba-1"}.Failure(errors.New("AccessDenied")))
+
+	if len(sink.events) != 2 {
+		t.Fatalf("sink received %d events, want 2", len(sink.events))
+	}
+	created := sink.events[0]
+	if created.Actor != "s3-iam-cosi-driver" || created.Outcome != OutcomeSuccess || created.Time.IsZero() {
+		t.Errorf("event was not stamped: %+v", created)
+	}
+	if created.Source == nil || created.Source.Name != "ba1" {
+		t.Errorf("source = %+v, want the BucketAccess of the context", created.Source)
+	}
+	if failed := sink.events[1]; failed.Outcome != OutcomeFailure || failed.Error != "AccessDenied" {
+		t.Errorf("failed event = %+v", failed)
+	}
+}
+
+func TestRecordMalformedPolicy(t *testing.T) {
+	var buf bytes.Buffer
+	installAuditor(t, &fileSink{w: &buf})
+
+	const malformed = `{"Statement": [`
+	Record(context.Background(), Event{
+		Action:       ActionPutBucketPolicy,
+		Bucket:       "bucket1",
+		PolicyBefore: json.RawMessage(malformed),
+		PolicyAfter:  json.RawMessage(`{"Statement":[]}`),
+	})
+
+	var event Event
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("audit line is not JSON: %v\n%s", err, buf.String())
+	}
+	var before string
+	if err := json.Unmarshal(event.PolicyBefore, &before); err != nil || before != malformed {
+		t.Errorf("policyBefore = %s, want the malformed policy as a string", event.PolicyBefore)
+	}
+	if string(event.PolicyAfter) != `{"Statement":[]}` {
+		t.Errorf("policyAfter = %s, want the valid policy unchanged", event.PolicyAfter)
+	}
+}
+
+func TestRecordWithoutAuditor(t *testing.T) {
+	SetDefault(nil)
+	// Must not panic
+	Record(context.Background(), Event{Action: ActionCreateUser})
+}
+
+func TestDiffPolicies(t *testing.T) {
+	const (
+		read  = `{"Action":"s3:GetObject","Effect":"Allow","Principal":{"AWS":"u1"}}`
+		write = `{"Action":"s3:PutObject","Effect":"Allow","Principal":{"AWS":"u2"}}`
+		// The statement read, with other key order and spacing
+		readReordered = `{ "Principal": {"AWS": "u1"}, "Effect": "Allow", "Action": "s3:GetObject" }`
+	)
+	policy := func(statements ...string) string {
+		return `{"Version":"2012-10-17","Statement":[` + strings.Join(statements, ",") + `]}`
+	}
+
+	tests := []struct {
+		name        string
+		before      string
+		after       string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{name: "first grant", before: "", after: policy(read), wantAdded: []string{read}},
+		{name: "grant added", before: policy(read), after: policy(readReordered, write), wantAdded: []string{write}},
+		{name: "grant removed", before: policy(read, write), after: policy(write), wantRemoved: []string{read}},
+		{name: "policy deleted", before: policy(read), after: "", wantRemoved: []string{read}},
+		{name: "unchanged", before: policy(read), after: policy(readReordered)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffPolicies(tt.before, tt.after)
+			if got := rawStrings(diff.Added); strings.Join(got, "\n") != strings.Join(tt.wantAdded, "\n") {
+				t.Errorf("added = %v, want %v", got, tt.wantAdded)
+			}
+			if got := rawStrings(diff.Removed); strings.Join(got, "\n") != strings.Join(tt.wantRemoved, "\n") {
+				t.Errorf("removed = %v, want %v", got, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func rawStrings(raw []json.RawMessage) []string {
+	var out []string
+	for _, r := range raw {
+		out = append(out, string(r))
+	}
+	return out
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	installAuditor(t, sink)
+
+	Record(context.Background(), Event{Action: ActionCreateUser, User: "cosi-user-ba-1"})
+	Record(context.Background(), Event{Action: ActionCreateAccessKey, User: "cosi-user-ba-1", AccessKeyID: "AKIA1"})
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line is not a JSON object: %q", scanner.Text())
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("audit log has %d lines, want one per event", len(lines))
+	}
+	first := lines[0]
+	for _, key := range []string{"time", "actor", "action", "outcome", "user"} {
+		if _, ok := first[key]; !ok {
+			t.Errorf("line lacks %q: %v", key, first)
+		}
+	}
+	for _, key := range []string{"error", "bucket", "policyBefore", "policyDiff", "source"} {
+		if _, ok := first[key]; ok {
+			t.Errorf("empty %q was written: %v", key, first)
+		}
+	}
+	if lines[1]["accessKeyId"] != "AKIA1" {
+		t.Errorf("second line = %v", lines[1])
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("webhook body is not an event: %v", err)
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, time.Second)
+	if err := sink.Write(&Event{Action: ActionDeleteRole, Role: "cosi-role-ba-1"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	select {
+	case event := <-received:
+		if event.Action != ActionDeleteRole || event.Role != "cosi-role-ba-1" {
+			t.Errorf("webhook received %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+}
+
+func TestWebhookSinkDropsWhenFull(t *testing.T) {
+	// Nothing drains the queue, as with a webhook that stopped answering
+	sink := &webhookSink{queue: make(chan *Event, webhookQueueSize)}
+	for i := 0; i < webhookQueueSize; i++ {
+		if err := sink.Write(&Event{Action: ActionCreateUser}); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if err := sink.Write(&Event{Action: ActionCreateUser}); err == nil {
+		t.Error("write to a full queue did not fail")
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// DiffPolicies compares two bucket policy documents statement by statement.
+// Either document may be empty, which is treated as a policy without statements.
+func DiffPolicies(before, after string) *PolicyDiff {
+	beforeStatements := policyStatements(before)
+	afterStatements := policyStatements(after)
+
+	diff := &PolicyDiff{}
+	for _, stmt := range afterStatements {
+		if !containsStatement(beforeStatements, stmt) {
+			diff.Added = append(diff.Added, stmt)
+		}
+	}
+	for _, stmt := range beforeStatements {
+		if !containsStatement(afterStatements, stmt) {
+			diff.Removed = append(diff.Removed, stmt)
+		}
+	}
+	return diff
+}
+
+// policyStatements returns the statements of a policy in their canonical JSON encoding
+func policyStatements(policy string) []json.RawMessage {
+	if policy == "" {
+		return nil
+	}
+
+	var doc struct {
+		Statement []interface{} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return nil
+	}
+
+	statements := make([]json.RawMessage, 0, len(doc.Statement))
+	for _, stmt := range doc.Statement {
+		// Marshalling the decoded value sorts map keys, so equal statements compare equal
+		canonical, err := json.Marshal(stmt)
+		if err != nil {
+			continue
+		}
+		statements = append(statements, canonical)
+	}
+	return statements
+}
+
+func containsStatement(statements []json.RawMessage, stmt json.RawMessage) bool {
+	for _, s := range statements {
+		if bytes.Equal(s, stmt) {
+			return true
+		}
+	}
+	return false
+}
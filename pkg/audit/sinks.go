@@ -0,0 +1,110 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// StdoutPath selects standard output as the destination of a file sink
+const StdoutPath = "-"
+
+// fileSink appends events as JSON lines to a file or stdout
+type fileSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewFileSink creates a sink that appends JSON lines to path, or to stdout when path is "-"
+func NewFileSink(path string) (Sink, error) {
+	if path == StdoutPath {
+		return &fileSink{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{w: f}, nil
+}
+
+func (s *fileSink) Write(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// webhookSink ships events to an HTTP endpoint in the background
+type webhookSink struct {
+	url    string
+	client *http.Client
+	queue  chan *Event
+}
+
+// webhookQueueSize bounds the number of events buffered for a slow webhook
+const webhookQueueSize = 1000
+
+// NewWebhookSink creates a sink that POSTs each event as JSON to url.
+// Delivery is asynchronous so a slow webhook never delays provisioning; events are dropped,
+// with an error logged, when the queue is full. Local sinks remain the system of record.
+func NewWebhookSink(url string, timeout time.Duration) Sink {
+	s := &webhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		queue:  make(chan *Event, webhookQueueSize),
+	}
+	go s.run()
+	return s
+}
+
+func (s *webhookSink) Write(event *Event) error {
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+		return fmt.Errorf("audit webhook queue is full, dropping %s event", event.Action)
+	}
+}
+
+func (s *webhookSink) run() {
+	for event := range s.queue {
+		if err := s.post(event); err != nil {
+			klog.ErrorS(err, "failed to deliver audit event to webhook", "url", s.url, "action", event.Action)
+		}
+	}
+}
+
+func (s *webhookSink) post(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -7,6 +7,9 @@ Licensed under the MIT License.
 package config
 
 import (
+	"strings"
+	"time"
+
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
@@ -35,3 +38,38 @@ func GetAllowedActions(accessMode string) ([]string, error) {
 	klog.InfoS("determined allowed actions", "actions", allowedActions)
 	return allowedActions, nil
 }
+
+// GetSessionDuration parses the lifetime of temporary credentials from BucketAccessClass parameters
+func GetSessionDuration(parameters map[string]string) (time.Duration, error) {
+	value := parameters[SessionDurationKey]
+	if value == "" {
+		return DefaultSessionDuration, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		klog.ErrorS(err, "invalid session duration", "value", value)
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s %q", SessionDurationKey, value)
+	}
+	if duration < MinSessionDuration || duration > MaxSessionDuration {
+		return 0, status.Errorf(codes.InvalidArgument, "%s must be between %s and %s", SessionDurationKey, MinSessionDuration, MaxSessionDuration)
+	}
+	return duration, nil
+}
+
+// GetCredentialFormats parses the additional credential formats requested by BucketAccessClass parameters
+func GetCredentialFormats(parameters map[string]string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(parameters[CredentialFormatsKey], ",") {
+		format = strings.TrimSpace(format)
+		switch format {
+		case "":
+			continue
+		case CredentialFormatAWS, CredentialFormatEnv, CredentialFormatRclone, CredentialFormatS3cmd, CredentialFormatBoto:
+			formats = append(formats, format)
+		default:
+			klog.ErrorS(nil, "invalid credential format", "format", format)
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s %q", CredentialFormatsKey, format)
+		}
+	}
+	return formats, nil
+}
@@ -6,6 +6,8 @@ Licensed under the MIT License.
 
 package config
 
+import "time"
+
 // Driver name and annotation constants
 const (
 	// DriverName is the name of the S3 IAM COSI driver
@@ -26,8 +28,100 @@ const (
 	AccessModeAdmin = "admin"
 )
 
-// Access mode annotation key
+// Access mode and sharing annotation keys
 const (
 	// AccessModeKey is the key used in annotations to specify the access mode
 	AccessModeKey = DriverName + "/access-mode"
+	// AllowedConsumerNamespacesKey lists, on a BucketClaim, the other namespaces that may access its bucket
+	AllowedConsumerNamespacesKey = DriverName + "/allowed-consumer-namespaces"
+)
+
+// BucketAccessClass parameters for IAM authentication
+const (
+	// SessionDurationKey sets the lifetime of temporary credentials, as a Go duration (e.g. "1h")
+	SessionDurationKey = "sessionDuration"
+	// RoleTrustPrincipalKey overrides the principal allowed to assume roles created by the driver
+	RoleTrustPrincipalKey = "roleTrustPrincipal"
+	// WebIdentityProviderKey is the ARN of the OIDC provider that federates Kubernetes ServiceAccounts
+	WebIdentityProviderKey = "webIdentityProvider"
+	// WebIdentityIssuerKey is the issuer URL of the cluster's ServiceAccount tokens
+	WebIdentityIssuerKey = "webIdentityIssuer"
+	// WebIdentityAudienceKey is the audience of tokens accepted by the role, required with
+	// WebIdentityProviderKey
+	WebIdentityAudienceKey = "webIdentityAudience"
+	// WebIdentityTokenFileKey is the path of the projected ServiceAccount token inside consuming pods,
+	// required with WebIdentityProviderKey
+	WebIdentityTokenFileKey = "webIdentityTokenFile"
+)
+
+// Defaults and limits for temporary credentials
+const (
+	// DefaultSessionDuration is used when the BucketAccessClass does not set SessionDurationKey
+	DefaultSessionDuration = time.Hour
+	// MinSessionDuration is the shortest session STS accepts
+	MinSessionDuration = 15 * time.Minute
+	// MaxSessionDuration is the longest session roles created by the driver allow
+	MaxSessionDuration = 12 * time.Hour
+)
+
+// Credentials Secret annotation and label keys
+const (
+	// CredentialsExpiryKey records when the temporary credentials in a credentials Secret expire
+	CredentialsExpiryKey = DriverName + "/credentials-expiry"
+	// CredentialsSecretLabel marks the credentials Secrets the driver maintains, so that only those
+	// are watched
+	CredentialsSecretLabel = DriverName + "/credentials"
+)
+
+// BucketAccessClass parameter selecting additional credential formats
+const (
+	// CredentialFormatsKey is a comma separated list of formats written to the credentials Secret
+	CredentialFormatsKey = "credentialFormats"
+)
+
+// Credential formats
+const (
+	// CredentialFormatAWS writes an AWS shared credentials file and config file
+	CredentialFormatAWS = "aws"
+	// CredentialFormatEnv writes AWS_* environment variables, for use with envFrom
+	CredentialFormatEnv = "env"
+	// CredentialFormatRclone writes an rclone remote configuration
+	CredentialFormatRclone = "rclone"
+	// CredentialFormatS3cmd writes an s3cmd configuration
+	CredentialFormatS3cmd = "s3cmd"
+	// CredentialFormatBoto writes a boto configuration
+	CredentialFormatBoto = "boto"
+)
+
+// BucketClass parameters setting a storage quota on created buckets
+const (
+	// QuotaBytesKey limits the size of a bucket, as a Kubernetes quantity (e.g. "100Gi")
+	QuotaBytesKey = "quotaBytes"
+	// QuotaObjectsKey limits the number of objects in a bucket
+	QuotaObjectsKey = "quotaObjects"
+)
+
+// BucketClass parameter naming created buckets
+const (
+	// BucketNamePatternKey is a template for backend bucket names, with the variables
+	// ${namespace}, ${claimName}, ${class} and ${hash}
+	BucketNamePatternKey = "bucketNamePattern"
+)
+
+// Tags the driver sets on the buckets and IAM users it creates, recording their Kubernetes provenance
+const (
+	TagClusterID       = DriverName + "/cluster-id"
+	TagNamespace       = DriverName + "/namespace"
+	TagBucket          = DriverName + "/bucket"
+	TagBucketClaim     = DriverName + "/bucket-claim"
+	TagBucketClaimUID  = DriverName + "/bucket-claim-uid"
+	TagBucketAccess    = DriverName + "/bucket-access"
+	TagBucketAccessUID = DriverName + "/bucket-access-uid"
+)
+
+// Class parameter adding tags to the buckets or IAM users created for the class
+const (
+	// TagsKey is a comma separated list of key=value tags, set on buckets when used in a BucketClass
+	// and on IAM users when used in a BucketAccessClass
+	TagsKey = "tags"
 )
@@ -0,0 +1,115 @@
+/*
+Copyright 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// S3 bucket name length limits
+const (
+	minBucketNameLength = 3
+	maxBucketNameLength = 63
+	// hashLength is the number of hex digits ${hash} expands to
+	hashLength = 8
+	// bucketNameSeparator replaces characters S3 does not allow and precedes the hash of truncated names
+	bucketNameSeparator = "-"
+)
+
+var (
+	patternVariable    = regexp.MustCompile(`\$\{([^}]*)\}`)
+	invalidBucketChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+	repeatedSeparators = regexp.MustCompile(`[.-]*\.[.-]*`)
+	validBucketName    = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+)
+
+// BucketNameVars are the values bucketNamePattern variables expand to
+type BucketNameVars struct {
+	Namespace string
+	ClaimName string
+	Class     string
+	// Name is the name of the Bucket CR, which ${hash} is derived from
+	Name string
+}
+
+// ResolveBucketName returns the backend name of the bucket requested with parameters. Without a
+// bucketNamePattern this is the Bucket CR name. Expanded values are lowercased and characters S3
+// does not allow are replaced with '-'. Names over 63 characters are truncated and suffixed with
+// the hash so that truncated names stay unique.
+func ResolveBucketName(parameters map[string]string, vars BucketNameVars) (string, error) {
+	pattern := parameters[BucketNamePatternKey]
+	if pattern == "" {
+		return vars.Name, nil
+	}
+
+	sum := sha256.Sum256([]byte(vars.Name))
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+
+	var unknown, missing string
+	name := patternVariable.ReplaceAllStringFunc(pattern, func(match string) string {
+		variable := patternVariable.FindStringSubmatch(match)[1]
+		var value string
+		switch variable {
+		case "namespace":
+			value = vars.Namespace
+		case "claimName":
+			value = vars.ClaimName
+		case "class":
+			value = vars.Class
+		case "hash":
+			value = hash
+		default:
+			unknown = variable
+		}
+		if value == "" && missing == "" {
+			missing = variable
+		}
+		return value
+	})
+	if unknown != "" {
+		return "", status.Errorf(codes.InvalidArgument, "unknown variable ${%s} in %s", unknown, BucketNamePatternKey)
+	}
+	if missing != "" {
+		return "", status.Errorf(codes.FailedPrecondition, "no value for ${%s} in %s", missing, BucketNamePatternKey)
+	}
+
+	name = invalidBucketChars.ReplaceAllString(strings.ToLower(name), bucketNameSeparator)
+	name = repeatedSeparators.ReplaceAllStringFunc(name, func(string) string { return "." })
+	name = strings.Trim(name, ".-")
+	if len(name) > maxBucketNameLength {
+		name = strings.Trim(name[:maxBucketNameLength-hashLength-len(bucketNameSeparator)], ".-") + bucketNameSeparator + hash
+	}
+
+	if err := validateBucketName(name); err != nil {
+		klog.ErrorS(err, "invalid bucket name", "pattern", pattern, "name", name)
+		return "", err
+	}
+	return name, nil
+}
+
+// validateBucketName applies the S3 bucket naming rules
+func validateBucketName(name string) error {
+	if len(name) < minBucketNameLength || len(name) > maxBucketNameLength {
+		return status.Errorf(codes.InvalidArgument, "bucket name %q must be between %d and %d characters",
+			name, minBucketNameLength, maxBucketNameLength)
+	}
+	if !validBucketName.MatchString(name) {
+		return status.Errorf(codes.InvalidArgument, "bucket name %q must start and end with a letter or digit", name)
+	}
+	if net.ParseIP(name) != nil {
+		return status.Errorf(codes.InvalidArgument, "bucket name %q must not be an IP address", name)
+	}
+	return nil
+}
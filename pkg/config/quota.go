@@ -0,0 +1,40 @@
+/*
+Copyright 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package config
+
+import (
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+// GetBucketQuota parses the storage quota requested by BucketClass parameters
+func GetBucketQuota(parameters map[string]string) (s3client.BucketQuota, error) {
+	var quota s3client.BucketQuota
+	if value := parameters[QuotaBytesKey]; value != "" {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil || quantity.Sign() <= 0 {
+			klog.ErrorS(err, "invalid bucket quota", "key", QuotaBytesKey, "value", value)
+			return quota, status.Errorf(codes.InvalidArgument, "invalid %s %q", QuotaBytesKey, value)
+		}
+		quota.MaxBytes = quantity.Value()
+	}
+	if value := parameters[QuotaObjectsKey]; value != "" {
+		objects, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || objects <= 0 {
+			klog.ErrorS(err, "invalid bucket quota", "key", QuotaObjectsKey, "value", value)
+			return quota, status.Errorf(codes.InvalidArgument, "invalid %s %q", QuotaObjectsKey, value)
+		}
+		quota.MaxObjects = objects
+	}
+	return quota, nil
+}
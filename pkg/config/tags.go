@@ -0,0 +1,45 @@
+/*
+Copyright 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package config
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Tag limits shared by S3 object tagging and IAM
+const (
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// GetExtraTags parses the admin supplied tags of class parameters. Keys under the driver name are
+// reserved for the provenance tags the driver sets itself.
+func GetExtraTags(parameters map[string]string) (map[string]string, error) {
+	tags := map[string]string{}
+	for _, pair := range strings.Split(parameters[TagsKey], ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s entry %q, expected key=value", TagsKey, pair)
+		}
+		if strings.HasPrefix(key, DriverName+"/") {
+			return nil, status.Errorf(codes.InvalidArgument, "%s key %q uses the reserved %s/ prefix", TagsKey, key, DriverName)
+		}
+		if len(key) > maxTagKeyLength || len(value) > maxTagValueLength {
+			return nil, status.Errorf(codes.InvalidArgument, "%s entry %q exceeds the tag length limits", TagsKey, pair)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	cosispec "sigs.k8s.io/container-object-storage-interface-spec"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+)
+
+// defaultRegion is written to formats that require a region when the driver reports none
+const defaultRegion = "us-east-1"
+
+// addCredentialFormats adds the rendered credential formats to the S3 credentials of a grant response
+func addCredentialFormats(credDetails map[string]*cosispec.CredentialDetails, formats []string) {
+	s3Creds, ok := credDetails["s3"]
+	if !ok || len(formats) == 0 {
+		return
+	}
+	for key, value := range renderCredentialFormats(s3Creds.Secrets, formats) {
+		s3Creds.Secrets[key] = value
+	}
+}
+
+// renderCredentialFormats renders the S3 credentials into the requested formats, keyed by the
+// name they are stored under in the credentials Secret
+func renderCredentialFormats(secretS3 map[string]string, formats []string) map[string]string {
+	creds := newFormatCredentials(secretS3)
+	rendered := map[string]string{}
+	for _, format := range formats {
+		switch format {
+		case config.CredentialFormatAWS:
+			rendered["credentials"] = creds.awsCredentials()
+			rendered["config"] = creds.awsConfig()
+		case config.CredentialFormatEnv:
+			for key, value := range creds.env() {
+				rendered[key] = value
+			}
+		case config.CredentialFormatRclone:
+			rendered["rclone.conf"] = creds.rclone()
+		case config.CredentialFormatS3cmd:
+			rendered["s3cfg"] = creds.s3cmd()
+		case config.CredentialFormatBoto:
+			rendered["boto"] = creds.boto()
+		}
+	}
+	return rendered
+}
+
+// formatCredentials holds the fields shared by all credential formats
+type formatCredentials struct {
+	accessKeyID          string
+	secretAccessKey      string
+	sessionToken         string
+	roleArn              string
+	webIdentityTokenFile string
+	stsEndpoint          string
+	endpoint             string
+	region               string
+	host                 string
+	port                 string
+	secure               bool
+}
+
+func newFormatCredentials(secretS3 map[string]string) *formatCredentials {
+	creds := &formatCredentials{
+		accessKeyID:          secretS3["accessKeyID"],
+		secretAccessKey:      secretS3["accessSecretKey"],
+		sessionToken:         secretS3["sessionToken"],
+		roleArn:              secretS3["roleArn"],
+		webIdentityTokenFile: secretS3["webIdentityTokenFile"],
+		stsEndpoint:          secretS3["stsEndpoint"],
+		endpoint:             secretS3["endpoint"],
+		region:               secretS3["region"],
+		secure:               true,
+	}
+	if creds.region == "" {
+		creds.region = defaultRegion
+	}
+	if u, err := url.Parse(creds.endpoint); err == nil && u.Host != "" {
+		creds.host = u.Host
+		creds.port = u.Port()
+		creds.secure = u.Scheme != "http"
+	}
+	return creds
+}
+
+// iniSection renders a section of an INI style file, skipping empty values
+func iniSection(name string, pairs ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", name)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			fmt.Fprintf(&b, "%s = %s\n", pairs[i], pairs[i+1])
+		}
+	}
+	return b.String()
+}
+
+func (c *formatCredentials) awsCredentials() string {
+	return iniSection("default",
+		"aws_access_key_id", c.accessKeyID,
+		"aws_secret_access_key", c.secretAccessKey,
+		"aws_session_token", c.sessionToken,
+	)
+}
+
+func (c *formatCredentials) awsConfig() string {
+	return iniSection("default",
+		"region", c.region,
+		"endpoint_url", c.endpoint,
+		"role_arn", c.roleArn,
+		"web_identity_token_file", c.webIdentityTokenFile,
+	)
+}
+
+func (c *formatCredentials) env() map[string]string {
+	env := map[string]string{
+		"AWS_ACCESS_KEY_ID":           c.accessKeyID,
+		"AWS_SECRET_ACCESS_KEY":       c.secretAccessKey,
+		"AWS_SESSION_TOKEN":           c.sessionToken,
+		"AWS_REGION":                  c.region,
+		"AWS_DEFAULT_REGION":          c.region,
+		"AWS_ENDPOINT_URL":            c.endpoint,
+		"AWS_ENDPOINT_URL_STS":        c.stsEndpoint,
+		"AWS_ROLE_ARN":                c.roleArn,
+		"AWS_WEB_IDENTITY_TOKEN_FILE": c.webIdentityTokenFile,
+	}
+	for key, value := range env {
+		if value == "" {
+			delete(env, key)
+		}
+	}
+	return env
+}
+
+func (c *formatCredentials) rclone() string {
+	envAuth := ""
+	if c.roleArn != "" {
+		envAuth = "true"
+	}
+	return iniSection("cosi",
+		"type", "s3",
+		"provider", "Other",
+		"env_auth", envAuth,
+		"access_key_id", c.accessKeyID,
+		"secret_access_key", c.secretAccessKey,
+		"session_token", c.sessionToken,
+		"endpoint", c.endpoint,
+		"region", c.region,
+	)
+}
+
+func (c *formatCredentials) s3cmd() string {
+	return iniSection("default",
+		"access_key", c.accessKeyID,
+		"secret_key", c.secretAccessKey,
+		"access_token", c.sessionToken,
+		"host_base", c.host,
+		"host_bucket", c.host,
+		"bucket_location", c.region,
+		"use_https", pythonBool(c.secure),
+	)
+}
+
+func (c *formatCredentials) boto() string {
+	host := c.host
+	if c.port != "" {
+		host = strings.TrimSuffix(host, ":"+c.port)
+	}
+	return iniSection("Credentials",
+		"aws_access_key_id", c.accessKeyID,
+		"aws_secret_access_key", c.secretAccessKey,
+		"aws_security_token", c.sessionToken,
+		"s3_host", host,
+		"s3_port", c.port,
+	) + "\n" + iniSection("Boto",
+		"is_secure", pythonBool(c.secure),
+	)
+}
+
+func pythonBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
@@ -0,0 +1,197 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/pdp"
+)
+
+// decisionCacheTTL is how long drift detection reuses the decision made for a BucketAccess, so
+// that the decision point is not asked about every granted access on every interval
+const decisionCacheTTL = 15 * time.Minute
+
+// cachedDecision is a decision made for a BucketAccess, with the input it was made on
+type cachedDecision struct {
+	input   string
+	actions []string
+	err     error
+	decided time.Time
+}
+
+// decisionCache remembers the decisions of the policy decision point by BucketAccess UID. A nil
+// cache remembers nothing.
+type decisionCache struct {
+	mu        sync.Mutex
+	decisions map[types.UID]*cachedDecision
+}
+
+func newDecisionCache() *decisionCache {
+	return &decisionCache{decisions: map[types.UID]*cachedDecision{}}
+}
+
+// get returns the decision made for uid on input if it is younger than decisionCacheTTL
+func (c *decisionCache) get(uid types.UID, input string) (*cachedDecision, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	decision, ok := c.decisions[uid]
+	if !ok || decision.input != input || time.Since(decision.decided) >= decisionCacheTTL {
+		return nil, false
+	}
+	return decision, true
+}
+
+// store remembers the decision made for uid on input
+func (c *decisionCache) store(uid types.UID, input string, actions []string, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.decisions[uid] = &cachedDecision{input: input, actions: actions, err: err, decided: time.Now()}
+}
+
+// expire drops the decisions older than decisionCacheTTL, including those of deleted BucketAccesses
+func (c *decisionCache) expire() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for uid, decision := range c.decisions {
+		if time.Since(decision.decided) >= decisionCacheTTL {
+			delete(c.decisions, uid)
+		}
+	}
+}
+
+// decisionInput identifies what a decision on bucketAccess was made on. A changed spec of the
+// BucketAccess or Bucket, a changed BucketAccessClass or other requested actions call for a new
+// decision; status updates, such as the grant itself, do not.
+func (s *provisionerServer) decisionInput(bucketAccess *objectstoragev1alpha1.BucketAccess,
+	bucketAccessClass *objectstoragev1alpha1.BucketAccessClass, bucketName string, actions []string) string {
+	var bucketGeneration int64
+	if bucket, ok := s.BucketIndex.Cached(bucketName); ok {
+		bucketGeneration = bucket.Generation
+	}
+	return fmt.Sprintf("%d/%s/%s/%d/%s", bucketAccess.Generation, bucketAccessClass.ResourceVersion,
+		bucketName, bucketGeneration, strings.Join(actions, ","))
+}
+
+// cachedDecideActions is decideActions for drift detection: a decision made on the same input
+// within decisionCacheTTL, on grant or by an earlier drift check, is reused
+func (s *provisionerServer) cachedDecideActions(ctx context.Context, bucketAccess *objectstoragev1alpha1.BucketAccess,
+	bucketAccessClass *objectstoragev1alpha1.BucketAccessClass, bucketName string, actions []string) ([]string, error) {
+	if s.DecisionPoint == nil {
+		return actions, nil
+	}
+	input := s.decisionInput(bucketAccess, bucketAccessClass, bucketName, actions)
+	if decision, ok := s.decisions.get(bucketAccess.UID, input); ok {
+		return decision.actions, decision.err
+	}
+	return s.decideActions(ctx, bucketAccess, bucketAccessClass, bucketName, actions)
+}
+
+// decideActions asks the policy decision point, if one is configured, whether bucketAccess may be
+// granted actions on bucketName, and returns the actions to grant. A denial is returned as
+// PermissionDenied; an unreachable decision point denies with Unavailable unless the driver
+// was told to fail open. The decision point may narrow the actions but not widen them: actions
+// outside those of the access mode deny the grant.
+func (s *provisionerServer) decideActions(ctx context.Context, bucketAccess *objectstoragev1alpha1.BucketAccess,
+	bucketAccessClass *objectstoragev1alpha1.BucketAccessClass, bucketName string, actions []string) ([]string, error) {
+	if s.DecisionPoint == nil {
+		return actions, nil
+	}
+
+	input := &pdp.Input{
+		BucketAccess:       bucketAccess,
+		BucketAccessClass:  bucketAccessClass,
+		BucketName:         bucketName,
+		AuthenticationType: string(bucketAccessClass.AuthenticationType),
+		Actions:            actions,
+	}
+	if bucket, ok := s.BucketIndex.Cached(bucketName); ok {
+		input.Bucket = bucket
+	}
+
+	decision, err := s.DecisionPoint.Decide(ctx, input)
+	if err != nil {
+		// Failures are not cached, the next drift check asks again
+		if s.DecisionFailOpen {
+			klog.ErrorS(err, "Policy decision point failed, granting requested actions",
+				"bucketAccess", bucketAccess.Name,
+				"namespace", bucketAccess.Namespace)
+			return actions, nil
+		}
+		klog.ErrorS(err, "Policy decision point failed, denying access",
+			"bucketAccess", bucketAccess.Name,
+			"namespace", bucketAccess.Namespace)
+		return nil, status.Error(codes.Unavailable, "policy decision point unavailable")
+	}
+
+	granted, err := decidedActions(decision, actions)
+	if err == nil && len(decision.Actions) > 0 {
+		klog.InfoS("Policy decision point modified granted actions",
+			"bucketAccess", bucketAccess.Name,
+			"namespace", bucketAccess.Namespace,
+			"requested", actions,
+			"granted", granted)
+	}
+	s.decisions.store(bucketAccess.UID, s.decisionInput(bucketAccess, bucketAccessClass, bucketName, actions), granted, err)
+	return granted, err
+}
+
+// decidedActions returns the actions decision grants out of requested
+func decidedActions(decision *pdp.Decision, requested []string) ([]string, error) {
+	if !decision.Allow {
+		reason := decision.Reason
+		if reason == "" {
+			reason = "denied by policy decision point"
+		}
+		return nil, status.Error(codes.PermissionDenied, reason)
+	}
+	if len(decision.Actions) == 0 {
+		return requested, nil
+	}
+	for _, action := range decision.Actions {
+		if !actionWithin(action, requested) {
+			return nil, status.Errorf(codes.PermissionDenied,
+				"policy decision point granted %s, which the access mode does not allow", action)
+		}
+	}
+	return decision.Actions, nil
+}
+
+// actionWithin reports whether action, which may hold wildcards, is covered by one of actions.
+// Action names are case-insensitive.
+func actionWithin(action string, actions []string) bool {
+	action = strings.ToLower(action)
+	for _, allowed := range actions {
+		if ok, _ := path.Match(strings.ToLower(allowed), action); ok {
+			return true
+		}
+	}
+	return false
+}
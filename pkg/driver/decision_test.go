@@ -0,0 +1,135 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/pdp"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+// stubDecisionPoint answers every question with the same decision and counts the questions
+type stubDecisionPoint struct {
+	decision *pdp.Decision
+	err      error
+	calls    int
+}
+
+func (p *stubDecisionPoint) Decide(ctx context.Context, input *pdp.Input) (*pdp.Decision, error) {
+	p.calls++
+	return p.decision, p.err
+}
+
+func testDecisionObjects() (*objectstoragev1alpha1.BucketAccess, *objectstoragev1alpha1.BucketAccessClass) {
+	bucketAccess := &objectstoragev1alpha1.BucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "ba", Namespace: "team-a", UID: "ba-uid", Generation: 1},
+	}
+	class := &objectstoragev1alpha1.BucketAccessClass{
+		ObjectMeta:         metav1.ObjectMeta{Name: "bac", ResourceVersion: "1"},
+		AuthenticationType: objectstoragev1alpha1.AuthenticationTypeKey,
+	}
+	return bucketAccess, class
+}
+
+func TestDecideActions(t *testing.T) {
+	requested := []string{"s3:GetObject", "s3:PutObject", "s3:ListBucket"}
+	tests := []struct {
+		name     string
+		decision *pdp.Decision
+		err      error
+		failOpen bool
+		actions  []string
+		want     []string
+		wantCode codes.Code
+	}{
+		{name: "allow", decision: &pdp.Decision{Allow: true}, want: requested},
+		{name: "narrow", decision: &pdp.Decision{Allow: true, Actions: []string{"s3:GetObject", "s3:ListBucket"}},
+			want: []string{"s3:GetObject", "s3:ListBucket"}},
+		{name: "narrow wildcard", decision: &pdp.Decision{Allow: true, Actions: []string{"s3:Get*"}},
+			actions: []string{"s3:*"}, want: []string{"s3:Get*"}},
+		{name: "widen", decision: &pdp.Decision{Allow: true, Actions: []string{"s3:GetObject", "s3:DeleteObject"}},
+			wantCode: codes.PermissionDenied},
+		{name: "widen with wildcard", decision: &pdp.Decision{Allow: true, Actions: []string{"s3:*"}},
+			wantCode: codes.PermissionDenied},
+		{name: "deny", decision: &pdp.Decision{Allow: false, Reason: "sandbox"}, wantCode: codes.PermissionDenied},
+		{name: "unreachable", err: errors.New("connection refused"), wantCode: codes.Unavailable},
+		{name: "unreachable fail open", err: errors.New("connection refused"), failOpen: true, want: requested},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestProvisioner(t, s3clienttest.NewServer(t), "")
+			s.DecisionPoint = &stubDecisionPoint{decision: tt.decision, err: tt.err}
+			s.DecisionFailOpen = tt.failOpen
+			bucketAccess, class := testDecisionObjects()
+			actions := tt.actions
+			if actions == nil {
+				actions = requested
+			}
+
+			got, err := s.decideActions(context.Background(), bucketAccess, class, "bucket1", actions)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("decideActions = %v, want %v", err, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("actions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedDecideActions(t *testing.T) {
+	s := newTestProvisioner(t, s3clienttest.NewServer(t), "")
+	decisionPoint := &stubDecisionPoint{decision: &pdp.Decision{Allow: true, Actions: []string{"s3:GetObject"}}}
+	s.DecisionPoint = decisionPoint
+	s.decisions = newDecisionCache()
+	bucketAccess, class := testDecisionObjects()
+	requested := []string{"s3:GetObject", "s3:PutObject"}
+	ctx := context.Background()
+
+	// The decision made on grant is reused by drift detection, also after status updates
+	if _, err := s.decideActions(ctx, bucketAccess, class, "bucket1", requested); err != nil {
+		t.Fatal(err)
+	}
+	bucketAccess.ResourceVersion = "2"
+	got, err := s.cachedDecideActions(ctx, bucketAccess, class, "bucket1", requested)
+	if err != nil || !reflect.DeepEqual(got, []string{"s3:GetObject"}) {
+		t.Errorf("cachedDecideActions = %v, %v, want the granted actions", got, err)
+	}
+	if decisionPoint.calls != 1 {
+		t.Errorf("decision point was asked %d times, want 1", decisionPoint.calls)
+	}
+
+	// A changed class calls for a new decision
+	class.ResourceVersion = "2"
+	if _, err := s.cachedDecideActions(ctx, bucketAccess, class, "bucket1", requested); err != nil {
+		t.Fatal(err)
+	}
+	if decisionPoint.calls != 2 {
+		t.Errorf("decision point was asked %d times after the class changed, want 2", decisionPoint.calls)
+	}
+
+	// Failures to reach the decision point are not cached
+	decisionPoint.err = errors.New("connection refused")
+	class.ResourceVersion = "3"
+	for i := 0; i < 2; i++ {
+		if _, err := s.cachedDecideActions(ctx, bucketAccess, class, "bucket1", requested); status.Code(err) != codes.Unavailable {
+			t.Fatalf("cachedDecideActions = %v, want Unavailable", err)
+		}
+	}
+	if decisionPoint.calls != 4 {
+		t.Errorf("decision point was asked %d times, want 4", decisionPoint.calls)
+	}
+}
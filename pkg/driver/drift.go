@@ -0,0 +1,140 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/metrics"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+// bucketGrants are the grants of the BucketAccesses bound to one bucket of one account
+type bucketGrants struct {
+	accountKey     string
+	bucketName     string
+	parameters     map[string]string
+	grants         []s3client.UserGrant
+	bucketAccesses map[string]*objectstoragev1alpha1.BucketAccess
+}
+
+// runDriftDetector periodically compares the bucket policies with the statements the granted
+// BucketAccesses call for, and restores statements that were removed or altered on the backend
+func (s *provisionerServer) runDriftDetector(ctx context.Context, interval time.Duration) {
+	klog.InfoS("Starting bucket policy drift detector", "interval", interval)
+	wait.UntilWithContext(ctx, s.reconcileBucketPolicies, interval)
+}
+
+func (s *provisionerServer) reconcileBucketPolicies(ctx context.Context) {
+	for _, desired := range s.desiredGrants(ctx) {
+		s3Client, _, err := s.ClientCache.GetClient(ctx, desired.parameters)
+		if err != nil {
+			klog.ErrorS(err, "failed to initialize clients for drift detection", "account", desired.accountKey)
+			continue
+		}
+		drifted, err := s3Client.ReconcileBucketPolicy(audit.WithSource(ctx, audit.Source{Kind: "Bucket", Name: desired.bucketName}),
+			desired.bucketName, desired.grants)
+		if err != nil {
+			klog.ErrorS(err, "failed to reconcile bucket policy", "bucketName", desired.bucketName)
+			continue
+		}
+		metrics.ObservePolicyDrift(desired.accountKey, len(drifted))
+		for _, userName := range drifted {
+			bucketAccess := desired.bucketAccesses[userName]
+			klog.InfoS("Restored drifted bucket policy statement",
+				"bucketName", desired.bucketName,
+				"userName", userName,
+				"bucketAccess", bucketAccess.Name,
+				"namespace", bucketAccess.Namespace)
+			s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonPolicyDrift,
+				"Statement of %s in the policy of bucket %s was removed or altered on the backend and has been restored",
+				userName, desired.bucketName)
+		}
+	}
+}
+
+// desiredGrants groups the IAM users of granted KEY authenticated BucketAccesses by bucket.
+// IAM authenticated accesses are left out, their roles carry the policy.
+// Actions are decided by the policy decision point as on grant, reusing recent decisions; accesses
+// it now denies are left as they are.
+func (s *provisionerServer) desiredGrants(ctx context.Context) map[string]*bucketGrants {
+	s.decisions.expire()
+	desired := map[string]*bucketGrants{}
+	for _, bucketAccess := range s.BucketAccessIndex.List() {
+		userName := bucketAccess.Status.AccountID
+		if !bucketAccess.Status.AccessGranted || bucketAccess.DeletionTimestamp != nil ||
+			!strings.HasPrefix(userName, s3client.UserNamePrefix) {
+			continue
+		}
+		class, err := s.BucketAccessClassLister.Get(bucketAccess.Spec.BucketAccessClassName)
+		if err != nil || class.DriverName != s.Provisioner {
+			continue
+		}
+		bucketName := s.bucketIDOf(bucketAccess)
+		if bucketName == "" {
+			continue
+		}
+		accountKey, err := s.ClientCache.AccountKey(class.Parameters)
+		if err != nil {
+			continue
+		}
+
+		accessMode := class.Annotations[config.AccessModeKey]
+		if accessMode == "" {
+			accessMode = config.AccessModeAdmin
+		}
+		actions, err := config.GetAllowedActions(accessMode)
+		if err != nil {
+			continue
+		}
+		actions, err = s.cachedDecideActions(ctx, bucketAccess, class, bucketName, actions)
+		if err != nil {
+			klog.V(3).InfoS("skipping drift check of bucket access", "bucketAccess", bucketAccess.Name,
+				"namespace", bucketAccess.Namespace, "reason", status.Convert(err).Message())
+			continue
+		}
+
+		key := accountKey + "/" + bucketName
+		grants, ok := desired[key]
+		if !ok {
+			grants = &bucketGrants{
+				accountKey:     accountKey,
+				bucketName:     bucketName,
+				parameters:     class.Parameters,
+				bucketAccesses: map[string]*objectstoragev1alpha1.BucketAccess{},
+			}
+			desired[key] = grants
+		}
+		grants.grants = append(grants.grants, s3client.UserGrant{UserName: userName, Actions: actions})
+		grants.bucketAccesses[userName] = bucketAccess
+	}
+	return desired
+}
+
+// bucketIDOf returns the backend name of the bucket bucketAccess is bound to, or "" if the bucket
+// is not provisioned yet or belongs to another driver
+func (s *provisionerServer) bucketIDOf(bucketAccess *objectstoragev1alpha1.BucketAccess) string {
+	name := s.bucketOf(bucketAccess)
+	if name == "" {
+		return ""
+	}
+	bucket, err := s.BucketLister.Get(name)
+	if err != nil || bucket.Spec.DriverName != s.Provisioner || bucket.Status.BucketID == "" {
+		return ""
+	}
+	return bucket.Status.BucketID
+}
@@ -24,13 +24,39 @@ package driver
 
 import (
 	"context"
+	"time"
 
 	"k8s.io/klog/v2"
 	cosispec "sigs.k8s.io/container-object-storage-interface-spec"
 )
 
-func NewDriver(ctx context.Context, driverName string) (cosispec.IdentityServer, cosispec.ProvisionerServer, error) {
-	provisionerServer, err := NewProvisionerServer(driverName)
+// Options configures optional driver behavior
+type Options struct {
+	// TenantConfig is the "namespace/name" of the ConfigMap mapping namespaces to accounts, empty to
+	// let every namespace use every account
+	TenantConfig string
+	// ClusterID identifies the cluster in the tags of the buckets and IAM users the driver creates
+	ClusterID string
+	// OrphanGC is the mode of the orphan garbage collector: off, dry-run or enforce
+	OrphanGC string
+	// OrphanGCInterval is how often the orphan garbage collector runs
+	OrphanGCInterval time.Duration
+	// PolicyDriftInterval is how often bucket policies are checked for drift, 0 to disable
+	PolicyDriftInterval time.Duration
+	// DryRun logs storage mutations instead of performing them, answering them with synthetic responses
+	DryRun bool
+	// PolicyDecisionURL is the webhook consulted before access is granted, empty to grant
+	// according to the BucketAccessClass alone
+	PolicyDecisionURL string
+	// PolicyDecisionFailOpen grants access when the policy decision point cannot be reached
+	PolicyDecisionFailOpen bool
+	// CredentialSources are the path and URL prefixes the file and http account credential sources
+	// may read, empty to only read account credentials from Secrets
+	CredentialSources []string
+}
+
+func NewDriver(ctx context.Context, driverName string, opts Options) (cosispec.IdentityServer, cosispec.ProvisionerServer, error) {
+	provisionerServer, err := NewProvisionerServer(ctx, driverName, opts)
 	if err != nil {
 		klog.Fatal(err, "failed to create provisioner server")
 		return nil, nil, err
@@ -40,5 +66,5 @@ func NewDriver(ctx context.Context, driverName string) (cosispec.IdentityServer,
 		klog.Fatal(err, "failed to create provisioner server")
 		return nil, nil, err
 	}
-	return identityServer, provisionerServer, nil
+	return identityServer, newInstrumentedProvisionerServer(provisionerServer), nil
 }
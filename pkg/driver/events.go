@@ -0,0 +1,92 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+	bucketscheme "sigs.k8s.io/container-object-storage-interface-api/client/clientset/versioned/scheme"
+)
+
+// Event reasons posted on BucketClaims and BucketAccesses
+const (
+	ReasonBucketCreated        = "BucketCreated"
+	ReasonBucketCreateFailed   = "BucketCreateFailed"
+	ReasonBucketDeleted        = "BucketDeleted"
+	ReasonBucketDeleteFailed   = "BucketDeleteFailed"
+	ReasonBucketNotEmpty       = "BucketNotEmpty"
+	ReasonPolicyUpdated        = "PolicyUpdated"
+	ReasonPolicyUpdateFailed   = "PolicyUpdateFailed"
+	ReasonAccessDenied         = "AccessDenied"
+	ReasonAccessRevoked        = "AccessRevoked"
+	ReasonRevokeFailed         = "RevokeFailed"
+	ReasonCredentialsRefreshed = "CredentialsRefreshed"
+	ReasonQuotaExceeded        = "QuotaExceeded"
+	ReasonBucketQuotaFailed    = "BucketQuotaFailed"
+	ReasonPolicyDrift          = "PolicyDrift"
+)
+
+// newEventRecorder creates a recorder that posts Events through the core API on behalf of the driver
+func newEventRecorder(clientset kubernetes.Interface, component string) record.EventRecorder {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		klog.ErrorS(err, "failed to register core types with event scheme")
+	}
+	if err := bucketscheme.AddToScheme(scheme); err != nil {
+		klog.ErrorS(err, "failed to register objectstorage types with event scheme")
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartStructuredLogging(4)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: clientset.CoreV1().Events(""),
+	})
+	return broadcaster.NewRecorder(scheme, corev1.EventSource{Component: component})
+}
+
+// bucketClaimRef returns a reference to the BucketClaim that a Bucket was provisioned for, if any
+func bucketClaimRef(bucket *objectstoragev1alpha1.Bucket) *corev1.ObjectReference {
+	if bucket == nil || bucket.Spec.BucketClaim == nil || bucket.Spec.BucketClaim.Name == "" {
+		return nil
+	}
+	ref := bucket.Spec.BucketClaim.DeepCopy()
+	if ref.Kind == "" {
+		ref.Kind = "BucketClaim"
+	}
+	if ref.APIVersion == "" {
+		ref.APIVersion = objectstoragev1alpha1.SchemeGroupVersion.String()
+	}
+	return ref
+}
+
+// claimNamespace returns the namespace of the BucketClaim a Bucket was provisioned for, if known
+func claimNamespace(ref *corev1.ObjectReference) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.Namespace
+}
+
+// recordEvent posts an Event on obj. It is a no-op when obj could not be resolved.
+func (s *provisionerServer) recordEvent(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if s.Recorder == nil || obj == nil {
+		return
+	}
+	if ref, ok := obj.(*corev1.ObjectReference); ok && ref == nil {
+		return
+	}
+	if ba, ok := obj.(*objectstoragev1alpha1.BucketAccess); ok && ba == nil {
+		return
+	}
+	s.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,246 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/metrics"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+// Modes of the orphan garbage collector
+const (
+	// GCModeOff disables the garbage collector
+	GCModeOff = "off"
+	// GCModeDryRun logs the orphans the garbage collector would delete
+	GCModeDryRun = "dry-run"
+	// GCModeEnforce deletes orphans and strips their bucket policy statements
+	GCModeEnforce = "enforce"
+)
+
+// orphanGracePeriod protects users created by grants that are still in flight
+const orphanGracePeriod = 15 * time.Minute
+
+// validateGCMode rejects unknown garbage collector modes. Enforcing requires a cluster ID: without
+// one every untagged cosi-user-* of an account counts as ours, including users of other drivers or
+// clusters created before tagging.
+func validateGCMode(mode, clusterID string) error {
+	switch mode {
+	case "", GCModeOff, GCModeDryRun:
+		return nil
+	case GCModeEnforce:
+		if clusterID == "" {
+			return fmt.Errorf("orphan garbage collector mode %s requires --cluster-id", GCModeEnforce)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown orphan garbage collector mode %q, expected %s, %s or %s", mode, GCModeOff, GCModeDryRun, GCModeEnforce)
+	}
+}
+
+// runOrphanCollector periodically finds IAM users created by the driver whose BucketAccess no longer
+// exists, e.g. after a failed revoke or when BucketAccesses were deleted while the driver was not
+// running. In enforce mode their statements are stripped from the bucket policies of the account
+// and the users are deleted.
+func (s *provisionerServer) runOrphanCollector(ctx context.Context, mode string, interval time.Duration) {
+	klog.InfoS("Starting orphan garbage collector", "mode", mode, "interval", interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		s.collectOrphans(ctx, mode == GCModeEnforce)
+	}, interval)
+}
+
+func (s *provisionerServer) collectOrphans(ctx context.Context, enforce bool) {
+	// BucketAccesses are listed from the API server rather than the informer, so that a cache that
+	// has not caught up cannot make a live user look orphaned
+	bucketAccesses, err := s.BucketClientset.ObjectstorageV1alpha1().BucketAccesses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.ErrorS(err, "failed to list bucket accesses, skipping orphan collection")
+		return
+	}
+	live := sets.New[string]()
+	for _, bucketAccess := range bucketAccesses.Items {
+		live.Insert(string(bucketAccess.UID))
+	}
+
+	for accountKey, parameters := range s.driverAccounts() {
+		if err := s.collectAccountOrphans(ctx, accountKey, parameters, live, enforce); err != nil {
+			klog.ErrorS(err, "failed to collect orphans", "account", accountKey)
+		}
+	}
+}
+
+// driverAccounts returns the parameters referencing each account used by the Buckets and
+// BucketAccessClasses of the driver, keyed by account
+func (s *provisionerServer) driverAccounts() map[string]map[string]string {
+	accounts := map[string]map[string]string{}
+	add := func(parameters map[string]string) {
+		key, err := s.ClientCache.AccountKey(parameters)
+		if err != nil {
+			return
+		}
+		if _, ok := accounts[key]; !ok {
+			accounts[key] = parameters
+		}
+	}
+
+	buckets, err := s.BucketLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list buckets")
+	}
+	for _, bucket := range buckets {
+		if bucket.Spec.DriverName == s.Provisioner {
+			add(bucket.Spec.Parameters)
+		}
+	}
+	classes, err := s.BucketAccessClassLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list bucket access classes")
+	}
+	for _, class := range classes {
+		if class.DriverName == s.Provisioner {
+			add(class.Parameters)
+		}
+	}
+	return accounts
+}
+
+func (s *provisionerServer) collectAccountOrphans(ctx context.Context, accountKey string, parameters map[string]string,
+	live sets.Set[string], enforce bool) error {
+	s3Client, _, err := s.ClientCache.GetClient(ctx, parameters)
+	if err != nil {
+		return err
+	}
+	users, err := s3Client.IAM.ListUsers(s3client.UserNamePrefix)
+	if err != nil {
+		return err
+	}
+
+	var orphans []*iam.User
+	for _, user := range users {
+		userName := aws.StringValue(user.UserName)
+		uid := strings.TrimPrefix(strings.TrimPrefix(userName, s3client.UserNamePrefix), "ba-")
+		if live.Has(uid) {
+			continue
+		}
+		if user.CreateDate != nil && time.Since(*user.CreateDate) < orphanGracePeriod {
+			continue
+		}
+		if !s.ownsUser(s3Client, userName) {
+			continue
+		}
+		orphans = append(orphans, user)
+	}
+
+	deleted := 0
+	defer func() {
+		metrics.ObserveOrphanedUsers(accountKey, len(orphans), deleted)
+	}()
+	if len(orphans) == 0 {
+		return nil
+	}
+	if !enforce {
+		for _, user := range orphans {
+			klog.InfoS("Found orphaned IAM user, not deleting in dry-run mode",
+				"account", accountKey,
+				"userName", aws.StringValue(user.UserName))
+		}
+		return nil
+	}
+
+	buckets := s.accountBuckets(s3Client, accountKey)
+	for _, user := range orphans {
+		userName := aws.StringValue(user.UserName)
+		stripUserStatements(ctx, s3Client, buckets, userName)
+		if err := s3Client.DeleteIAMUser(ctx, userName); err != nil {
+			klog.ErrorS(err, "failed to delete orphaned IAM user", "userName", userName)
+			continue
+		}
+		klog.InfoS("Deleted orphaned IAM user", "account", accountKey, "userName", userName)
+		deleted++
+	}
+	return nil
+}
+
+// accountBuckets returns the buckets of the account the driver may have granted access to: those
+// of its Bucket CRs, and those tagged with its cluster ID, which includes buckets whose CR is gone.
+// Other buckets of the account are never touched.
+func (s *provisionerServer) accountBuckets(s3Client *s3client.S3Client, accountKey string) []string {
+	buckets := sets.New[string]()
+	crs, err := s.BucketLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list buckets")
+	}
+	for _, bucket := range crs {
+		if bucket.Spec.DriverName != s.Provisioner || bucket.Status.BucketID == "" {
+			continue
+		}
+		if key, err := s.ClientCache.AccountKey(bucket.Spec.Parameters); err == nil && key == accountKey {
+			buckets.Insert(bucket.Status.BucketID)
+		}
+	}
+
+	listed, err := s3Client.S3.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		klog.ErrorS(err, "failed to list buckets of account, only using Bucket CRs", "account", accountKey)
+		return sets.List(buckets)
+	}
+	for _, bucket := range listed.Buckets {
+		name := aws.StringValue(bucket.Name)
+		if buckets.Has(name) {
+			continue
+		}
+		tags, err := s3Client.GetBucketTagging(name)
+		if err != nil {
+			klog.V(4).InfoS("skipping bucket whose tags cannot be read", "bucketName", name, "err", err)
+			continue
+		}
+		if tags[config.TagClusterID] == s.ClusterID {
+			buckets.Insert(name)
+		}
+	}
+	return sets.List(buckets)
+}
+
+// ownsUser reports whether the user was created by this cluster. With a cluster ID only users
+// tagged with it qualify; without one, users tagged by any cluster are left alone.
+func (s *provisionerServer) ownsUser(s3Client *s3client.S3Client, userName string) bool {
+	tags, err := s3Client.IAM.ListUserTags(userName)
+	if err != nil {
+		klog.ErrorS(err, "failed to list user tags, skipping user", "userName", userName)
+		return false
+	}
+	clusterID, tagged := tags[config.TagClusterID]
+	if s.ClusterID != "" {
+		return clusterID == s.ClusterID
+	}
+	return !tagged
+}
+
+// stripUserStatements removes the statements granting userName access from the policies of buckets.
+// A bucket that fails, e.g. because it was deleted meanwhile, does not keep the others from being
+// cleaned; a statement left behind only names a user that no longer exists.
+func stripUserStatements(ctx context.Context, s3Client *s3client.S3Client, buckets []string, userName string) {
+	for _, bucketName := range buckets {
+		if err := s3Client.RemoveUserFromBucketPolicy(ctx, bucketName, userName); err != nil {
+			klog.ErrorS(err, "failed to strip orphaned user from bucket policy", "bucketName", bucketName, "userName", userName)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestValidateGCMode(t *testing.T) {
+	tests := []struct {
+		mode, clusterID string
+		wantErr         bool
+	}{
+		{mode: "", wantErr: false},
+		{mode: GCModeOff, wantErr: false},
+		{mode: GCModeDryRun, wantErr: false},
+		{mode: GCModeEnforce, clusterID: "c1", wantErr: false},
+		{mode: GCModeEnforce, wantErr: true},
+		{mode: "always", clusterID: "c1", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateGCMode(tt.mode, tt.clusterID); (err != nil) != tt.wantErr {
+			t.Errorf("validateGCMode(%q, %q) = %v, want error %v", tt.mode, tt.clusterID, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCollectOrphansOnlyStripsDriverBuckets(t *testing.T) {
+	backend := s3clienttest.NewServer(t)
+	created := time.Now().Add(-time.Hour)
+	const orphan = s3client.UserNamePrefix + "ba-gone"
+	backend.AddUser(orphan, created, map[string]string{config.TagClusterID: "c1"})
+	backend.AddUser(s3client.UserNamePrefix+"ba-other", created, map[string]string{config.TagClusterID: "c2"})
+	for _, name := range []string{"known", "tagged", "foreign"} {
+		backend.AddBucket(name, "")
+	}
+
+	// "known" belongs to a Bucket CR, "missing" to one whose bucket is gone from the backend
+	bucketCR := func(name, bucketID string) *objectstoragev1alpha1.Bucket {
+		return &objectstoragev1alpha1.Bucket{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       objectstoragev1alpha1.BucketSpec{DriverName: config.DriverName, Parameters: testAccountParameters()},
+			Status:     objectstoragev1alpha1.BucketStatus{BucketReady: true, BucketID: bucketID},
+		}
+	}
+	s := newTestProvisioner(t, backend, s3client.ProviderMinIO, bucketCR("bc-1", "known"), bucketCR("bc-2", "missing"))
+	s.ClusterID = "c1"
+
+	ctx := context.Background()
+	client, _, err := s.ClientCache.GetClient(ctx, testAccountParameters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutBucketTagging(ctx, "tagged", map[string]string{config.TagClusterID: "c1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"known", "tagged", "foreign"} {
+		if err := client.AddUserToBucketPolicy(ctx, name, orphan, []string{"s3:GetObject"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.collectOrphans(ctx, true)
+
+	if _, ok := backend.User(orphan); ok {
+		t.Error("orphaned user of this cluster was not deleted")
+	}
+	if _, ok := backend.User(s3client.UserNamePrefix + "ba-other"); !ok {
+		t.Error("user of another cluster was deleted")
+	}
+	for _, name := range []string{"known", "tagged"} {
+		if bucket, _ := backend.Bucket(name); strings.Contains(bucket.Policy, orphan) {
+			t.Errorf("statement of the orphan was left in the policy of %s:\n%s", name, bucket.Policy)
+		}
+	}
+	if bucket, _ := backend.Bucket("foreign"); !strings.Contains(bucket.Policy, orphan) {
+		t.Error("the policy of a bucket the driver does not manage was changed")
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+	cosispec "sigs.k8s.io/container-object-storage-interface-spec"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+// roleName returns the name of the IAM role created for a BucketAccess
+func roleName(bucketAccessId string) string {
+	return s3client.RoleNamePrefix + bucketAccessId
+}
+
+// roleTrustPrincipal returns the principal allowed to assume roles, defaulting to the root of the
+// account, which is named by its numeric AccountID
+func roleTrustPrincipal(parameters map[string]string, s3Params *s3client.S3ClientParams) (string, error) {
+	if principal := parameters[config.RoleTrustPrincipalKey]; principal != "" {
+		return principal, nil
+	}
+	if s3Params.AccountID == "" {
+		return "", status.Errorf(codes.FailedPrecondition,
+			"the account secret must set AccountID, or the BucketAccessClass %s", config.RoleTrustPrincipalKey)
+	}
+	return fmt.Sprintf("arn:aws:iam::%s:root", s3Params.AccountID), nil
+}
+
+// roleTrustPolicy returns the trust policy of the role created for bucketAccess: bound to its
+// ServiceAccount when web identity federation is configured, otherwise to the trust principal
+func roleTrustPolicy(parameters map[string]string, s3Params *s3client.S3ClientParams,
+	bucketAccess *objectstoragev1alpha1.BucketAccess) (s3client.RoleDocument, error) {
+	provider := parameters[config.WebIdentityProviderKey]
+	if provider == "" {
+		principal, err := roleTrustPrincipal(parameters, s3Params)
+		if err != nil {
+			return s3client.RoleDocument{}, err
+		}
+		return s3client.NewAssumeRoleTrustPolicy(principal), nil
+	}
+
+	issuer := parameters[config.WebIdentityIssuerKey]
+	if issuer == "" {
+		return s3client.RoleDocument{}, status.Errorf(codes.InvalidArgument, "%s requires %s", config.WebIdentityProviderKey, config.WebIdentityIssuerKey)
+	}
+	// The default ServiceAccount token is issued for the API server; pods must mount a dedicated
+	// projected token whose audience the role checks
+	audience := parameters[config.WebIdentityAudienceKey]
+	if audience == "" || parameters[config.WebIdentityTokenFileKey] == "" {
+		return s3client.RoleDocument{}, status.Errorf(codes.InvalidArgument, "%s requires %s and %s of a projected ServiceAccount token",
+			config.WebIdentityProviderKey, config.WebIdentityAudienceKey, config.WebIdentityTokenFileKey)
+	}
+	if bucketAccess == nil || bucketAccess.Spec.ServiceAccountName == "" {
+		return s3client.RoleDocument{}, status.Error(codes.InvalidArgument, "web identity federation requires the BucketAccess to set serviceAccountName")
+	}
+	subject := fmt.Sprintf("system:serviceaccount:%s:%s", bucketAccess.Namespace, bucketAccess.Spec.ServiceAccountName)
+	return s3client.NewWebIdentityTrustPolicy(provider, issuer, subject, audience), nil
+}
+
+// usesWebIdentity reports whether roles for the BucketAccessClass are assumed by pods themselves
+func usesWebIdentity(parameters map[string]string) bool {
+	return parameters[config.WebIdentityProviderKey] != ""
+}
+
+// grantRoleAccess implements IAM authentication: instead of an IAM user with permanent keys, a role
+// scoped to the bucket is created and temporary session credentials for it are returned
+func (s *provisionerServer) grantRoleAccess(ctx context.Context, s3Client *s3client.S3Client, s3Params *s3client.S3ClientParams,
+	bucketAccess *objectstoragev1alpha1.BucketAccess, bucketAccessClass *objectstoragev1alpha1.BucketAccessClass,
+	bucketName, bucketAccessId string, allowedActions []string) (*cosispec.DriverGrantBucketAccessResponse, error) {
+	duration, err := config.GetSessionDuration(bucketAccessClass.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	name := roleName(bucketAccessId)
+	trustPolicy, err := roleTrustPolicy(bucketAccessClass.Parameters, s3Params, bucketAccess)
+	if err != nil {
+		s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonAccessDenied, "%s", status.Convert(err).Message())
+		return nil, err
+	}
+	role, err := s3Client.EnsureBucketRole(ctx, name, trustPolicy, bucketName, allowedActions)
+	if err != nil {
+		s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonPolicyUpdateFailed, "Failed to provision IAM role %s", name)
+		return nil, err
+	}
+
+	if usesWebIdentity(bucketAccessClass.Parameters) {
+		klog.InfoS("Successfully granted bucket access through web identity federation",
+			"bucketName", bucketName,
+			"roleName", name,
+			"serviceAccount", bucketAccess.Spec.ServiceAccountName)
+		s.recordEvent(bucketAccess, corev1.EventTypeNormal, ReasonPolicyUpdated,
+			"Granted role %s access to bucket %s for ServiceAccount %s", name, bucketName, bucketAccess.Spec.ServiceAccountName)
+		return &cosispec.DriverGrantBucketAccessResponse{
+			AccountId:   name,
+			Credentials: fetchWebIdentityCredentials(aws.StringValue(role.Arn), bucketAccessClass.Parameters[config.WebIdentityTokenFileKey], s3Params, ""),
+		}, nil
+	}
+
+	creds, err := s3Client.AssumeBucketRole(aws.StringValue(role.Arn), bucketAccessId, duration)
+	if err != nil {
+		s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonPolicyUpdateFailed, "Failed to assume IAM role %s", name)
+		return nil, err
+	}
+
+	klog.InfoS("Successfully granted bucket access with temporary credentials",
+		"bucketName", bucketName,
+		"roleName", name,
+		"expiration", aws.TimeValue(creds.Expiration))
+	s.recordEvent(bucketAccess, corev1.EventTypeNormal, ReasonPolicyUpdated,
+		"Granted role %s access to bucket %s with credentials valid for %s", name, bucketName, duration)
+	return &cosispec.DriverGrantBucketAccessResponse{
+		AccountId:   name,
+		Credentials: fetchSessionCredentials(creds, s3Params.GetFullEndpoint(), ""),
+	}, nil
+}
+
+// fetchSessionCredentials builds the credential map for temporary STS credentials
+func fetchSessionCredentials(creds *sts.Credentials, endpoint string, region string) map[string]*cosispec.CredentialDetails {
+	credDetails := fetchUserCredentials(
+		aws.StringValue(creds.AccessKeyId),
+		aws.StringValue(creds.SecretAccessKey),
+		endpoint,
+		region,
+	)
+	credDetails["s3"].Secrets["sessionToken"] = aws.StringValue(creds.SessionToken)
+	credDetails["s3"].Secrets["expiration"] = aws.TimeValue(creds.Expiration).UTC().Format(time.RFC3339)
+	return credDetails
+}
+
+// fetchWebIdentityCredentials builds the credential map for web identity federation. It carries no
+// keys: pods exchange their projected token for credentials of roleArn with AssumeRoleWithWebIdentity.
+// The sidecar only keeps the keys, so the refresher writes the other fields into the Secret.
+func fetchWebIdentityCredentials(roleArn, tokenFile string, s3Params *s3client.S3ClientParams, region string) map[string]*cosispec.CredentialDetails {
+	credDetails := fetchUserCredentials("", "", s3Params.GetFullEndpoint(), region)
+	credDetails["s3"].Secrets["roleArn"] = roleArn
+	credDetails["s3"].Secrets["webIdentityTokenFile"] = tokenFile
+	credDetails["s3"].Secrets["stsEndpoint"] = s3Params.GetFullSTSEndpoint()
+	return credDetails
+}
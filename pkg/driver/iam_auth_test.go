@@ -0,0 +1,237 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestRoleTrustPrincipal(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		accountID  string
+		want       string
+		code       codes.Code
+	}{
+		{
+			name:      "account root from AccountID",
+			accountID: "123456789012",
+			want:      "arn:aws:iam::123456789012:root",
+		},
+		{
+			name:       "explicit principal",
+			parameters: map[string]string{config.RoleTrustPrincipalKey: "arn:aws:iam::210987654321:user/ops"},
+			want:       "arn:aws:iam::210987654321:user/ops",
+		},
+		{
+			name: "no AccountID",
+			code: codes.FailedPrecondition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &s3client.S3ClientParams{AccountName: "account1", AccountID: tt.accountID}
+			got, err := roleTrustPrincipal(tt.parameters, params)
+			if status.Code(err) != tt.code {
+				t.Fatalf("got error %v, want code %s", err, tt.code)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// credentialsSecret returns a credentials Secret as written by the sidecar, holding secretS3
+func credentialsSecret(t *testing.T, created time.Time, secretS3 map[string]string) *corev1.Secret {
+	t.Helper()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "ba-creds",
+			Namespace:         "team-a",
+			CreationTimestamp: metav1.NewTime(created),
+		},
+	}
+	if secretS3 != nil {
+		if err := updateBucketInfo(secret, secretS3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return secret
+}
+
+func TestSessionRefreshDue(t *testing.T) {
+	now := time.Now()
+	withToken := map[string]string{"accessKeyID": "ASIA1", "accessSecretKey": "secret", "sessionToken": "token"}
+
+	expiring := credentialsSecret(t, now.Add(-2*time.Hour), withToken)
+	expiring.Annotations = map[string]string{config.CredentialsExpiryKey: now.Add(10 * time.Minute).Format(time.RFC3339)}
+
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+		want   bool
+	}{
+		{"written by sidecar without token", credentialsSecret(t, now, map[string]string{"accessKeyID": "ASIA1", "accessSecretKey": "secret"}), true},
+		{"without BucketInfo", credentialsSecret(t, now, nil), true},
+		{"fresh session", credentialsSecret(t, now, withToken), false},
+		{"session in its last quarter", expiring, true},
+		{"session of an old Secret", credentialsSecret(t, now.Add(-50*time.Minute), withToken), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionRefreshDue(tt.secret, time.Hour, now); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshWritesSessionTokenImmediately(t *testing.T) {
+	backend := s3clienttest.NewServer(t)
+	backend.AddRole("cosi-role-ba-1", "{}")
+
+	// The sidecar copied only the keys of the grant into the Secret
+	secret := credentialsSecret(t, time.Now(), map[string]string{"accessKeyID": "ASIA1", "accessSecretKey": "secret"})
+	s := newTestProvisioner(t, backend, s3client.ProviderCephRGW, secret)
+
+	bucketAccess := &objectstoragev1alpha1.BucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "ba", Namespace: "team-a", UID: "1"},
+		Spec:       objectstoragev1alpha1.BucketAccessSpec{CredentialsSecretName: "ba-creds"},
+		Status:     objectstoragev1alpha1.BucketAccessStatus{AccessGranted: true, AccountID: "cosi-role-ba-1"},
+	}
+	bucketAccessClass := &objectstoragev1alpha1.BucketAccessClass{
+		DriverName:         config.DriverName,
+		AuthenticationType: objectstoragev1alpha1.AuthenticationTypeIAM,
+		Parameters:         testAccountParameters(),
+	}
+
+	if err := s.refreshCredentialsSecret(context.Background(), bucketAccess, bucketAccessClass); err != nil {
+		t.Fatalf("refreshCredentialsSecret: %v", err)
+	}
+
+	updated, err := s.Clientset.CoreV1().Secrets("team-a").Get(context.Background(), "ba-creds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretS3, err := readBucketInfo(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secretS3["sessionToken"] == "" {
+		t.Error("session token was not written")
+	}
+	if secretS3["accessKeyID"] == "ASIA1" {
+		t.Error("access key was not replaced by the one of the new session")
+	}
+	if updated.Annotations[config.CredentialsExpiryKey] == "" {
+		t.Error("expiry annotation was not written")
+	}
+	if n := backend.Count("sts:AssumeRole"); n != 1 {
+		t.Errorf("AssumeRole called %d times, want 1", n)
+	}
+}
+
+func TestRoleTrustPolicyWebIdentity(t *testing.T) {
+	bucketAccess := &objectstoragev1alpha1.BucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "ba", Namespace: "team-a"},
+		Spec:       objectstoragev1alpha1.BucketAccessSpec{ServiceAccountName: "app"},
+	}
+	parameters := map[string]string{
+		config.WebIdentityProviderKey:  "arn:aws:iam::123456789012:oidc-provider/oidc.example.com",
+		config.WebIdentityIssuerKey:    "https://oidc.example.com",
+		config.WebIdentityAudienceKey:  "sts.amazonaws.com",
+		config.WebIdentityTokenFileKey: "/var/run/secrets/tokens/s3-token",
+	}
+
+	policy, err := roleTrustPolicy(parameters, &s3client.S3ClientParams{}, bucketAccess)
+	if err != nil {
+		t.Fatalf("roleTrustPolicy: %v", err)
+	}
+	conditions := policy.Statement[0].Condition["StringEquals"]
+	if got := conditions["oidc.example.com:sub"]; got != "system:serviceaccount:team-a:app" {
+		t.Errorf("subject condition = %q", got)
+	}
+	if got := conditions["oidc.example.com:aud"]; got != "sts.amazonaws.com" {
+		t.Errorf("audience condition = %q", got)
+	}
+
+	for _, key := range []string{config.WebIdentityAudienceKey, config.WebIdentityTokenFileKey} {
+		incomplete := map[string]string{}
+		for k, v := range parameters {
+			if k != key {
+				incomplete[k] = v
+			}
+		}
+		if _, err := roleTrustPolicy(incomplete, &s3client.S3ClientParams{}, bucketAccess); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("without %s: got %v, want InvalidArgument", key, err)
+		}
+	}
+}
+
+func TestRefreshWritesWebIdentityConfig(t *testing.T) {
+	backend := s3clienttest.NewServer(t)
+	backend.AddRole("cosi-role-ba-1", "{}")
+
+	secret := credentialsSecret(t, time.Now(), map[string]string{"accessKeyID": "", "accessSecretKey": ""})
+	s := newTestProvisioner(t, backend, s3client.ProviderCephRGW, secret)
+
+	bucketAccess := &objectstoragev1alpha1.BucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "ba", Namespace: "team-a", UID: "1"},
+		Spec:       objectstoragev1alpha1.BucketAccessSpec{CredentialsSecretName: "ba-creds", ServiceAccountName: "app"},
+		Status:     objectstoragev1alpha1.BucketAccessStatus{AccessGranted: true, AccountID: "cosi-role-ba-1"},
+	}
+	parameters := testAccountParameters()
+	parameters[config.WebIdentityProviderKey] = "arn:aws:iam::123456789012:oidc-provider/oidc.example.com"
+	parameters[config.WebIdentityTokenFileKey] = "/var/run/secrets/tokens/s3-token"
+	bucketAccessClass := &objectstoragev1alpha1.BucketAccessClass{
+		DriverName:         config.DriverName,
+		AuthenticationType: objectstoragev1alpha1.AuthenticationTypeIAM,
+		Parameters:         parameters,
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.refreshCredentialsSecret(context.Background(), bucketAccess, bucketAccessClass); err != nil {
+			t.Fatalf("refreshCredentialsSecret: %v", err)
+		}
+	}
+
+	updated, err := s.Clientset.CoreV1().Secrets("team-a").Get(context.Background(), "ba-creds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretS3, err := readBucketInfo(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := secretS3["roleArn"], s3clienttest.RoleARN("cosi-role-ba-1"); got != want {
+		t.Errorf("roleArn = %q, want %q", got, want)
+	}
+	if got := secretS3["webIdentityTokenFile"]; got != "/var/run/secrets/tokens/s3-token" {
+		t.Errorf("webIdentityTokenFile = %q", got)
+	}
+	if got := secretS3["stsEndpoint"]; got != backend.URL {
+		t.Errorf("stsEndpoint = %q, want %q", got, backend.URL)
+	}
+	// The second pass finds the configuration in place
+	if n := backend.Count("iam:GetRole"); n != 1 {
+		t.Errorf("GetRole called %d times, want 1", n)
+	}
+}
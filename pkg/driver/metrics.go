@@ -0,0 +1,59 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"time"
+
+	cosispec "sigs.k8s.io/container-object-storage-interface-spec"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/metrics"
+)
+
+// instrumentedProvisionerServer records request count and latency for every provisioner RPC
+type instrumentedProvisionerServer struct {
+	next cosispec.ProvisionerServer
+}
+
+var _ cosispec.ProvisionerServer = &instrumentedProvisionerServer{}
+
+func newInstrumentedProvisionerServer(next cosispec.ProvisionerServer) cosispec.ProvisionerServer {
+	return &instrumentedProvisionerServer{next: next}
+}
+
+func (s *instrumentedProvisionerServer) DriverCreateBucket(ctx context.Context,
+	req *cosispec.DriverCreateBucketRequest) (*cosispec.DriverCreateBucketResponse, error) {
+	start := time.Now()
+	rsp, err := s.next.DriverCreateBucket(ctx, req)
+	metrics.ObserveRPC("DriverCreateBucket", start, err)
+	return rsp, err
+}
+
+func (s *instrumentedProvisionerServer) DriverDeleteBucket(ctx context.Context,
+	req *cosispec.DriverDeleteBucketRequest) (*cosispec.DriverDeleteBucketResponse, error) {
+	start := time.Now()
+	rsp, err := s.next.DriverDeleteBucket(ctx, req)
+	metrics.ObserveRPC("DriverDeleteBucket", start, err)
+	return rsp, err
+}
+
+func (s *instrumentedProvisionerServer) DriverGrantBucketAccess(ctx context.Context,
+	req *cosispec.DriverGrantBucketAccessRequest) (*cosispec.DriverGrantBucketAccessResponse, error) {
+	start := time.Now()
+	rsp, err := s.next.DriverGrantBucketAccess(ctx, req)
+	metrics.ObserveRPC("DriverGrantBucketAccess", start, err)
+	return rsp, err
+}
+
+func (s *instrumentedProvisionerServer) DriverRevokeBucketAccess(ctx context.Context,
+	req *cosispec.DriverRevokeBucketAccessRequest) (*cosispec.DriverRevokeBucketAccessResponse, error) {
+	start := time.Now()
+	rsp, err := s.next.DriverRevokeBucketAccess(ctx, req)
+	metrics.ObserveRPC("DriverRevokeBucketAccess", start, err)
+	return rsp, err
+}
@@ -27,20 +27,34 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
 	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/pdp"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/tenant"
 	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/k8s"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/logging"
 	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	bucketclientset "sigs.k8s.io/container-object-storage-interface-api/client/clientset/versioned"
+	bucketinformers "sigs.k8s.io/container-object-storage-interface-api/client/informers/externalversions"
+	bucketlisters "sigs.k8s.io/container-object-storage-interface-api/client/listers/objectstorage/v1alpha1"
 	cosispec "sigs.k8s.io/container-object-storage-interface-spec"
 )
 
@@ -48,15 +62,29 @@ import (
 // 1.) for AdminOps : mainly for user related operations
 // 2.) for S3 operations : mainly for bucket related operations
 type provisionerServer struct {
-	Provisioner     string
-	Clientset       *kubernetes.Clientset
-	KubeConfig      *rest.Config
-	BucketClientset bucketclientset.Interface
+	Provisioner             string
+	Clientset               kubernetes.Interface
+	KubeConfig              *rest.Config
+	BucketClientset         bucketclientset.Interface
+	ClientCache             *s3client.ClientCache
+	BucketAccessIndex       *k8s.BucketAccessIndex
+	BucketIndex             *k8s.BucketIndex
+	BucketLister            bucketlisters.BucketLister
+	BucketClaimLister       bucketlisters.BucketClaimLister
+	BucketAccessClassLister bucketlisters.BucketAccessClassLister
+	CredentialsSecretLister corev1listers.SecretLister
+	Recorder                record.EventRecorder
+	Tenants                 *tenant.Enforcer
+	ClusterID               string
+	DecisionPoint           pdp.DecisionPoint
+	DecisionFailOpen        bool
+
+	decisions *decisionCache
 }
 
 var _ cosispec.ProvisionerServer = &provisionerServer{}
 
-func NewProvisionerServer(provisioner string) (cosispec.ProvisionerServer, error) {
+func NewProvisionerServer(ctx context.Context, provisioner string, opts Options) (cosispec.ProvisionerServer, error) {
 	kubeConfig, err := rest.InClusterConfig()
 	if err != nil {
 		kubeConfigPath := filepath.Join(os.Getenv("HOME"), ".kube", "config")
@@ -77,12 +105,79 @@ func NewProvisionerServer(provisioner string) (cosispec.ProvisionerServer, error
 		return nil, err
 	}
 
-	return &provisionerServer{
-		Provisioner:     provisioner,
-		Clientset:       clientset,
-		KubeConfig:      kubeConfig,
-		BucketClientset: bucketClientset,
-	}, nil
+	if err := validateGCMode(opts.OrphanGC, opts.ClusterID); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		klog.InfoS("Running in dry-run mode, storage mutations are logged and not performed")
+	}
+
+	var tenants *tenant.Enforcer
+	if opts.TenantConfig != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(opts.TenantConfig)
+		if err != nil || namespace == "" {
+			return nil, fmt.Errorf("tenant config must be namespace/name, got %q", opts.TenantConfig)
+		}
+		tenants, err = tenant.NewEnforcer(ctx, clientset, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bucketInformers := bucketinformers.NewSharedInformerFactory(bucketClientset, 0)
+	bucketAccessIndex, err := k8s.NewBucketAccessIndex(bucketInformers, bucketClientset)
+	if err != nil {
+		return nil, err
+	}
+	bucketIndex, err := k8s.NewBucketIndex(bucketInformers, bucketClientset)
+	if err != nil {
+		return nil, err
+	}
+	bucketLister := bucketInformers.Objectstorage().V1alpha1().Buckets().Lister()
+	bucketClaimLister := bucketInformers.Objectstorage().V1alpha1().BucketClaims().Lister()
+	bucketAccessClassLister := bucketInformers.Objectstorage().V1alpha1().BucketAccessClasses().Lister()
+	bucketInformers.Start(ctx.Done())
+	// Grants read claims, buckets and classes from the listers; an unsynced cache would report
+	// existing objects as missing and deny valid grants
+	for typ, synced := range bucketInformers.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("failed to sync informer for %v", typ)
+		}
+	}
+	credentialsSecretLister := newCredentialsSecretLister(ctx, clientset)
+
+	server := &provisionerServer{
+		Provisioner:             provisioner,
+		Clientset:               clientset,
+		KubeConfig:              kubeConfig,
+		BucketClientset:         bucketClientset,
+		ClientCache:             s3client.NewClientCache(ctx, clientset, opts.DryRun, opts.CredentialSources),
+		BucketAccessIndex:       bucketAccessIndex,
+		BucketIndex:             bucketIndex,
+		BucketLister:            bucketLister,
+		BucketClaimLister:       bucketClaimLister,
+		BucketAccessClassLister: bucketAccessClassLister,
+		CredentialsSecretLister: credentialsSecretLister,
+		Recorder:                newEventRecorder(clientset, provisioner),
+		Tenants:                 tenants,
+		ClusterID:               opts.ClusterID,
+		DecisionFailOpen:        opts.PolicyDecisionFailOpen,
+	}
+	if opts.PolicyDecisionURL != "" {
+		klog.InfoS("Consulting policy decision point before granting access", "url", opts.PolicyDecisionURL)
+		server.DecisionPoint = pdp.NewWebhook(opts.PolicyDecisionURL)
+		server.decisions = newDecisionCache()
+	}
+	go server.runCredentialRefresher(ctx)
+	if opts.OrphanGC == GCModeDryRun || opts.OrphanGC == GCModeEnforce {
+		go server.runOrphanCollector(ctx, opts.OrphanGC, opts.OrphanGCInterval)
+	}
+	// Policies are never written in dry-run mode, so every grant would look drifted
+	if opts.PolicyDriftInterval > 0 && !opts.DryRun {
+		go server.runDriftDetector(ctx, opts.PolicyDriftInterval)
+	}
+
+	return server, nil
 }
 
 // ProvisionerCreateBucket is an idempotent method for creating buckets
@@ -95,61 +190,122 @@ func NewProvisionerServer(provisioner string) (cosispec.ProvisionerServer, error
 //	non-nil err -           Internal error                                [requeue'd with exponential backoff]
 func (s *provisionerServer) DriverCreateBucket(ctx context.Context,
 	req *cosispec.DriverCreateBucketRequest) (*cosispec.DriverCreateBucketResponse, error) {
-	klog.Infof("req %v", req)
-
-	bucketName := req.GetName()
-	klog.InfoS("Creating Bucket", "name", bucketName)
+	klog.InfoS("DriverCreateBucket request", logging.RequestFields(req)...)
 
+	crName := req.GetName()
 	parameters := req.GetParameters()
 
-	s3Client, err := s3client.InitializeClients(ctx, s.Clientset, parameters)
+	// The Bucket CR is used to find the BucketClaim to post events on, check tenancy against
+	// and name the backend bucket after
+	var claimRef *corev1.ObjectReference
+	nameVars := config.BucketNameVars{Name: crName}
+	bucket, err := s.BucketLister.Get(crName)
+	if err != nil {
+		bucket, err = s.BucketClientset.ObjectstorageV1alpha1().Buckets().Get(ctx, crName, metav1.GetOptions{})
+	}
+	if err == nil {
+		claimRef = bucketClaimRef(bucket)
+		nameVars.Class = bucket.Spec.BucketClassName
+		if claimRef != nil {
+			nameVars.Namespace = claimRef.Namespace
+			nameVars.ClaimName = claimRef.Name
+		}
+	}
+
+	bucketName, err := config.ResolveBucketName(parameters, nameVars)
+	if err != nil {
+		s.recordEvent(claimRef, corev1.EventTypeWarning, ReasonBucketCreateFailed, "Failed to name bucket: %v", status.Convert(err).Message())
+		return nil, err
+	}
+	klog.InfoS("Creating Bucket", "name", bucketName, "bucket", crName)
+
+	if err := s.authorizeTenant(claimRef, claimNamespace(claimRef), parameters); err != nil {
+		return nil, err
+	}
+
+	s3Client, s3Params, err := s.ClientCache.GetClient(ctx, parameters)
 	if err != nil {
 		klog.ErrorS(err, "Failed to initialize clients")
-		return nil, status.Error(codes.Internal, "Failed to initialize clients")
+		return nil, err
 	}
 
+	if err := s.checkBucketQuota(claimRef, crName, claimNamespace(claimRef), parameters, s3Params); err != nil {
+		return nil, err
+	}
+
+	// Refuse storage quotas the provider cannot enforce before creating anything
+	storageQuota, err := config.GetBucketQuota(parameters)
+	if err != nil {
+		return nil, err
+	}
+	if !storageQuota.IsZero() && s3Client.Admin == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "provider %q does not support bucket quotas", s3Params.Profile.Name)
+	}
+	extraTags, err := config.GetExtraTags(parameters)
+	if err != nil {
+		return nil, err
+	}
+	tags := s.bucketTags(extraTags, crName, claimRef)
+	ctx = audit.WithSource(ctx, audit.Source{Kind: "Bucket", Name: crName})
+
 	err = s3Client.CreateBucket(bucketName)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			klog.InfoS("DEBUG: after s3 call", "ok", ok, "aerr", aerr)
 			switch aerr.Code() {
 			case s3.ErrCodeBucketAlreadyExists:
+				// A resolved name can be taken by a bucket of another account, which must not be
+				// reported as created
+				if parameters[config.BucketNamePatternKey] != "" {
+					klog.InfoS("Bucket name is taken by another account", "name", bucketName)
+					s.recordEvent(claimRef, corev1.EventTypeWarning, ReasonBucketCreateFailed,
+						"Bucket name %s is taken on the backend, add ${hash} to %s", bucketName, config.BucketNamePatternKey)
+					return nil, status.Errorf(codes.AlreadyExists, "bucket name %s is taken on the backend", bucketName)
+				}
 				klog.InfoS("Bucket already exists", "name", bucketName)
 				return &cosispec.DriverCreateBucketResponse{
 					BucketId: bucketName,
 				}, nil
 			case s3.ErrCodeBucketAlreadyOwnedByYou:
 				klog.InfoS("Bucket already owned by you", "name", bucketName)
+				// A name shared by another Bucket CR means the pattern does not tell the claims apart
+				if owners := s.BucketIndex.Owners(bucketName, crName); len(owners) > 0 {
+					s.recordEvent(claimRef, corev1.EventTypeWarning, ReasonBucketCreateFailed,
+						"Bucket name %s is already used by %s, add ${hash} to %s", bucketName, owners[0], config.BucketNamePatternKey)
+					return nil, status.Errorf(codes.AlreadyExists, "bucket %s already belongs to %s", bucketName, owners[0])
+				}
+				if err := s.setBucketQuota(ctx, claimRef, s3Client, bucketName, storageQuota); err != nil {
+					return nil, err
+				}
+				tagBucket(ctx, s3Client, bucketName, tags)
 				return &cosispec.DriverCreateBucketResponse{
 					BucketId: bucketName,
 				}, nil
 			}
 		}
 		klog.ErrorS(err, "Failed to create bucket", "bucketName", bucketName)
+		s.recordEvent(claimRef, corev1.EventTypeWarning, ReasonBucketCreateFailed, "Failed to create bucket %s: %v", bucketName, err)
 		return nil, status.Error(codes.Internal, "Failed to create bucket")
 	}
 
-	// TODO: this broke recently on Scale S3 (Noobaa).  It now self-locks the bucket.
-	// We need to figure out how to set the policy correctly.
-	/*
-		// Set initial bucket policy to deny all actions
-		// Create a simple deny-all policy that matches the exact format
-		policyJSON := s3client.NewRawDenyAllPolicy(bucketName)
-
-		// Log the policy for debugging
-		klog.InfoS("Setting bucket policy", "policy", string(policyJSON))
-
-		// Try using the policy
-		_, err = s3Client.PutRawBucketPolicy(bucketName, policyJSON)
-		if err != nil {
-			klog.ErrorS(err, "failed to set initial deny-all policy")
+	// Start from a deny-all policy that the first grant replaces, on providers where the account
+	// keeps control of the bucket. Scale S3 (Noobaa) applies it to the account and self-locks the bucket.
+	if s3Params.Profile.DenyAllBaseline {
+		if err := s3Client.PutDenyAllBaseline(ctx, bucketName, ""); err != nil {
+			klog.ErrorS(err, "failed to set initial deny-all policy", "bucketName", bucketName)
 			// Don't return error here, as the bucket was created successfully
 		} else {
 			klog.InfoS("Successfully set initial deny-all policy", "bucketName", bucketName)
 		}
-	*/
+	}
+
+	if err := s.setBucketQuota(ctx, claimRef, s3Client, bucketName, storageQuota); err != nil {
+		return nil, err
+	}
+	tagBucket(ctx, s3Client, bucketName, tags)
 
 	klog.InfoS("Successfully created Backend Bucket", "bucketName", bucketName)
+	s.recordEvent(claimRef, corev1.EventTypeNormal, ReasonBucketCreated, "Created bucket %s", bucketName)
 
 	return &cosispec.DriverCreateBucketResponse{
 		BucketId: bucketName,
@@ -158,71 +314,82 @@ func (s *provisionerServer) DriverCreateBucket(ctx context.Context,
 
 func (s *provisionerServer) DriverDeleteBucket(ctx context.Context,
 	req *cosispec.DriverDeleteBucketRequest) (*cosispec.DriverDeleteBucketResponse, error) {
-	klog.V(5).Infof("req %v", req)
+	klog.V(5).InfoS("DriverDeleteBucket request", logging.RequestFields(req)...)
 	bucketName := req.GetBucketId()
 	klog.V(3).InfoS("Deleting Bucket", "name", bucketName)
-	bucket, err := s.BucketClientset.ObjectstorageV1alpha1().Buckets().Get(ctx, bucketName, metav1.GetOptions{})
+	bucket, err := s.BucketIndex.Find(ctx, bucketName)
 	if err != nil {
-		klog.ErrorS(err, "failed to get bucket", "bucketName", bucketName)
-		return nil, status.Error(codes.Internal, "failed to get bucket")
+		return nil, err
 	}
 
 	parameters := bucket.Spec.Parameters
-	s3Client, err := s3client.InitializeClients(ctx, s.Clientset, parameters)
+	claimRef := bucketClaimRef(bucket)
+	if err := s.authorizeTenant(claimRef, claimNamespace(claimRef), parameters); err != nil {
+		return nil, err
+	}
+
+	s3Client, _, err := s.ClientCache.GetClient(ctx, parameters)
 	if err != nil {
 		klog.ErrorS(err, "failed to initialize clients")
-		return nil, status.Error(codes.Internal, "failed to initialize clients")
+		return nil, err
 	}
 
 	_, err = s3Client.DeleteBucket(bucketName)
 	if err != nil {
 		klog.ErrorS(err, "failed to delete bucket", "bucketName", bucketName)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "BucketNotEmpty" {
+			s.recordEvent(claimRef, corev1.EventTypeWarning, ReasonBucketNotEmpty, "Bucket %s is not empty and cannot be deleted", bucketName)
+		} else {
+			s.recordEvent(claimRef, corev1.EventTypeWarning, ReasonBucketDeleteFailed, "Failed to delete bucket %s: %v", bucketName, err)
+		}
 		return nil, status.Error(codes.Internal, "failed to delete bucket")
 	}
 	klog.InfoS("Successfully deleted Backend Bucket", "bucketName", bucketName)
+	s.recordEvent(claimRef, corev1.EventTypeNormal, ReasonBucketDeleted, "Deleted bucket %s", bucketName)
 	return &cosispec.DriverDeleteBucketResponse{}, nil
 }
 
 func (s *provisionerServer) DriverGrantBucketAccess(ctx context.Context,
 	req *cosispec.DriverGrantBucketAccessRequest) (*cosispec.DriverGrantBucketAccessResponse, error) {
-	klog.Infof("req %v", req)
+	klog.InfoS("DriverGrantBucketAccess request", logging.RequestFields(req)...)
 	bucketName := req.GetBucketId()
 	bucketAccessId := req.GetName()
 
 	// Generate username with format: cosi-user-<bucketclaim>-<random>
-	userName := fmt.Sprintf("cosi-user-%s", bucketAccessId)
+	userName := s3client.UserNamePrefix + bucketAccessId
 	klog.Info("Granting user accessPolicy to bucket ", "userName: ", userName, "bucketName: ", bucketName)
 
-	// Get parameters and initialize S3 client
+	// Get bucket access and class information
 	parameters := req.GetParameters()
-	accountSecretName, namespace, err := s3client.FetchSecretNameAndNamespace(parameters)
+	// A BucketAccess the informer has not seen yet is most likely in the namespace of the claim of the bucket
+	var hint types.NamespacedName
+	if bucket, ok := s.BucketIndex.Cached(bucketName); ok && bucket.Spec.BucketClaim != nil {
+		hint.Namespace = bucket.Spec.BucketClaim.Namespace
+	}
+	bucketAccess, bucketAccessClass, err := k8s.GetBucketAccessAndClass(ctx, s.BucketAccessIndex, s.BucketAccessClassLister, bucketAccessId, hint)
 	if err != nil {
 		return nil, err
 	}
 
-	accountSecret, err := s.Clientset.CoreV1().Secrets(namespace).Get(ctx, accountSecretName, metav1.GetOptions{})
-	if err != nil {
-		klog.ErrorS(err, "failed to get CES account secret")
-		return nil, status.Error(codes.Internal, "failed to get ces account secret")
+	if err := s.authorizeTenant(bucketAccess, bucketAccess.Namespace, parameters); err != nil {
+		return nil, err
 	}
-
-	s3Params, err := s3client.FetchParameters(accountSecret.Data)
-	if err != nil {
-		klog.ErrorS(err, "failed to fetch S3 parameters from secret")
+	if err := s.authorizeConsumer(bucketAccess); err != nil {
 		return nil, err
 	}
 
-	s3Client, err := s3client.InitializeClients(ctx, s.Clientset, parameters)
+	// Initialize S3 client
+	s3Client, s3Params, err := s.ClientCache.GetClient(ctx, parameters)
 	if err != nil {
 		klog.ErrorS(err, "failed to initialize clients")
-		return nil, status.Error(codes.Internal, "failed to initialize clients")
-	}
-
-	// Get bucket access and class information
-	_, bucketAccessClass, err := k8s.GetBucketAccessAndClass(ctx, s.BucketClientset, bucketAccessId)
-	if err != nil {
 		return nil, err
 	}
+	ctx = audit.WithSource(ctx, audit.Source{
+		Kind:      "BucketAccess",
+		Namespace: bucketAccess.Namespace,
+		Name:      bucketAccess.Name,
+		UID:       string(bucketAccess.UID),
+	})
 
 	// Get access mode and determine allowed actions
 	accessMode := bucketAccessClass.Annotations[config.AccessModeKey]
@@ -233,38 +400,91 @@ func (s *provisionerServer) DriverGrantBucketAccess(ctx context.Context,
 
 	allowedActions, err := config.GetAllowedActions(accessMode)
 	if err != nil {
+		s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonAccessDenied,
+			"Access mode %q requested by BucketAccessClass %s is not supported", accessMode, bucketAccessClass.Name)
+		return nil, err
+	}
+
+	credentialFormats, err := config.GetCredentialFormats(parameters)
+	if err != nil {
+		return nil, err
+	}
+	extraTags, err := config.GetExtraTags(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Let the policy decision point deny the grant or change its actions before anything is written
+	allowedActions, err = s.decideActions(ctx, bucketAccess, bucketAccessClass, bucketName, allowedActions)
+	if err != nil {
+		if status.Code(err) == codes.PermissionDenied {
+			s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonAccessDenied,
+				"Access to bucket %s denied by policy decision point: %s", bucketName, status.Convert(err).Message())
+		}
+		return nil, err
+	}
+
+	isIAM := req.GetAuthenticationType() == cosispec.AuthenticationType_IAM
+	if err := s.checkGrantQuota(parameters, s3Params, bucketAccess, !isIAM); err != nil {
 		return nil, err
 	}
 
+	if isIAM {
+		resp, err := s.grantRoleAccess(ctx, s3Client, s3Params, bucketAccess, bucketAccessClass, bucketName, bucketAccessId, allowedActions)
+		if err != nil {
+			return nil, err
+		}
+		addConnectionDetails(resp.Credentials, bucketName, s3Params)
+		addCredentialFormats(resp.Credentials, credentialFormats)
+		return resp, nil
+	}
+
 	// Create or get IAM user and access key
 	accessKey, err := s3Client.EnsureIAMUser(ctx, userName)
 	if err != nil {
+		s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonPolicyUpdateFailed, "Failed to provision IAM user %s", userName)
 		return nil, err
 	}
+	tagUser(ctx, s3Client, userName, s.userTags(extraTags, bucketAccess))
 
 	// Add user to bucket policy
 	klog.InfoS("adding user to bucket policy", "bucketName", bucketName, "userName", userName, "actions", allowedActions)
-	err = s3Client.AddUserToBucketPolicy(bucketName, userName, allowedActions)
+	err = s3Client.AddUserToBucketPolicy(ctx, bucketName, userName, allowedActions)
 	if err != nil {
 		klog.ErrorS(err, "failed to add user to bucket policy", "bucketName", bucketName, "userName", userName)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "AccessDenied" {
+			s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonAccessDenied,
+				"Storage backend denied updating the policy of bucket %s: %s", bucketName, aerr.Message())
+		} else {
+			s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonPolicyUpdateFailed,
+				"Failed to add %s to the policy of bucket %s: %v", userName, bucketName, err)
+		}
+		if st, ok := status.FromError(err); ok && st.Code() == codes.ResourceExhausted {
+			return nil, err
+		}
 		return nil, status.Error(codes.Internal, "failed to add user to bucket policy")
 	}
 
 	klog.InfoS("Successfully granted bucket access", "bucketName", bucketName, "accessMode", accessMode)
+	s.recordEvent(bucketAccess, corev1.EventTypeNormal, ReasonPolicyUpdated,
+		"Granted %s access (%s) to bucket %s", userName, accessMode, bucketName)
+	credentials := fetchUserCredentials(
+		*accessKey.AccessKeyId,
+		*accessKey.SecretAccessKey,
+		s3Params.GetFullEndpoint(),
+		"",
+	)
+	addConnectionDetails(credentials, bucketName, s3Params)
+	addCredentialFormats(credentials, credentialFormats)
 	return &cosispec.DriverGrantBucketAccessResponse{
-		AccountId: userName,
-		Credentials: fetchUserCredentials(
-			*accessKey.AccessKeyId,
-			*accessKey.SecretAccessKey,
-			s3Params.GetFullEndpoint(),
-			"",
-		),
+		AccountId:   userName,
+		Credentials: credentials,
 	}, nil
 }
 
 func (s *provisionerServer) DriverRevokeBucketAccess(ctx context.Context,
 	req *cosispec.DriverRevokeBucketAccessRequest) (*cosispec.DriverRevokeBucketAccessResponse, error) {
-	klog.Infof("req %v", req)
+	klog.InfoS("DriverRevokeBucketAccess request", logging.RequestFields(req)...)
 
 	userName := req.GetAccountId()
 	bucketName := req.GetBucketId()
@@ -273,51 +493,79 @@ func (s *provisionerServer) DriverRevokeBucketAccess(ctx context.Context,
 		"bucketName", bucketName)
 
 	// Get the bucket to find the bucket claim name
-	bucket, err := s.BucketClientset.ObjectstorageV1alpha1().Buckets().Get(ctx, bucketName, metav1.GetOptions{})
+	bucket, err := s.BucketIndex.Find(ctx, bucketName)
 	if err != nil {
-		klog.ErrorS(err, "failed to get bucket", "bucketName", bucketName)
-		return nil, status.Error(codes.Internal, "failed to get bucket")
+		return nil, err
 	}
 
 	parameters := bucket.Spec.Parameters
-	accountSecretName, namespace, err := s3client.FetchSecretNameAndNamespace(parameters)
-	if err != nil {
-		return nil, err
-	}
 
-	accountSecret, err := s.Clientset.CoreV1().Secrets(namespace).Get(ctx, accountSecretName, metav1.GetOptions{})
-	if err != nil {
-		klog.ErrorS(err, "failed to get CES account secret")
-		return nil, status.Error(codes.Internal, "failed to get ces account secret")
+	// Post events on the BucketAccess while it still exists, otherwise on the BucketClaim
+	claimRef := bucketClaimRef(bucket)
+	var eventTarget runtime.Object = claimRef
+	namespace := claimNamespace(claimRef)
+	bucketAccessId := strings.TrimPrefix(strings.TrimPrefix(userName, s3client.UserNamePrefix), s3client.RoleNamePrefix)
+	if bucketAccess, ok := s.BucketAccessIndex.Cached(bucketAccessId); ok {
+		eventTarget = bucketAccess
+		namespace = bucketAccess.Namespace
+		ctx = audit.WithSource(ctx, audit.Source{
+			Kind:      "BucketAccess",
+			Namespace: bucketAccess.Namespace,
+			Name:      bucketAccess.Name,
+			UID:       string(bucketAccess.UID),
+		})
+	} else if claimRef != nil {
+		ctx = audit.WithSource(ctx, audit.Source{
+			Kind:      claimRef.Kind,
+			Namespace: claimRef.Namespace,
+			Name:      claimRef.Name,
+			UID:       string(claimRef.UID),
+		})
 	}
 
-	_, err = s3client.FetchParameters(accountSecret.Data)
-	if err != nil {
+	if err := s.authorizeTenant(eventTarget, namespace, parameters); err != nil {
 		return nil, err
 	}
 
-	s3Client, err := s3client.InitializeClients(ctx, s.Clientset, parameters)
+	s3Client, _, err := s.ClientCache.GetClient(ctx, parameters)
 	if err != nil {
 		klog.ErrorS(err, "failed to initialize clients")
-		return nil, status.Error(codes.Internal, "failed to initialize clients")
+		return nil, err
 	}
+
+	// Roles of IAM authenticated accesses carry their own policy, so deleting the role revokes access
+	if strings.HasPrefix(userName, s3client.RoleNamePrefix) {
+		err = s3Client.DeleteBucketRole(ctx, userName)
+		if err != nil {
+			klog.ErrorS(err, "failed to delete IAM role", "roleName", userName)
+			s.recordEvent(eventTarget, corev1.EventTypeWarning, ReasonRevokeFailed, "Failed to delete IAM role %s: %v", userName, err)
+			return nil, status.Error(codes.Internal, "failed to delete IAM role")
+		}
+		s.recordEvent(eventTarget, corev1.EventTypeNormal, ReasonAccessRevoked, "Revoked access of role %s to bucket %s", userName, bucketName)
+		return &cosispec.DriverRevokeBucketAccessResponse{}, nil
+	}
+
 	// Remove user from bucket policy
-	err = s3Client.RemoveUserFromBucketPolicy(bucketName, userName)
+	err = s3Client.RemoveUserFromBucketPolicy(ctx, bucketName, userName)
 	if err != nil {
 		klog.ErrorS(err, "failed to remove user from bucket policy",
 			"userName", userName,
 			"bucketName", bucketName)
+		s.recordEvent(eventTarget, corev1.EventTypeWarning, ReasonRevokeFailed,
+			"Failed to remove %s from the policy of bucket %s: %v", userName, bucketName, err)
 		return nil, status.Error(codes.Internal, "failed to remove user from bucket policy")
 	}
 
 	// Delete the IAM user
-	err = s3Client.IAM.DeleteUser(userName)
+	err = s3Client.DeleteIAMUser(ctx, userName)
 	if err != nil {
 		klog.ErrorS(err, "failed to delete IAM user",
 			"userName", userName)
+		s.recordEvent(eventTarget, corev1.EventTypeWarning, ReasonRevokeFailed, "Failed to delete IAM user %s: %v", userName, err)
 		return nil, status.Error(codes.Internal, "failed to delete IAM user")
 	}
 
+	s.recordEvent(eventTarget, corev1.EventTypeNormal, ReasonAccessRevoked, "Revoked access of %s to bucket %s", userName, bucketName)
 	return &cosispec.DriverRevokeBucketAccessResponse{}, nil
 }
 
@@ -334,3 +582,28 @@ func fetchUserCredentials(accessKey, secretKey, endpoint string, region string)
 	credDetails["s3"] = creds
 	return credDetails
 }
+
+// addConnectionDetails adds what consumers need to reach the bucket besides the keys: its name, the
+// addressing style the backend requires and the CA bundle that signed the endpoint certificate
+func addConnectionDetails(credDetails map[string]*cosispec.CredentialDetails, bucketName string, s3Params *s3client.S3ClientParams) {
+	s3Creds, ok := credDetails["s3"]
+	if !ok {
+		return
+	}
+	for k, v := range connectionDetails(bucketName, s3Params) {
+		s3Creds.Secrets[k] = v
+	}
+}
+
+// connectionDetails returns the bucketName, pathStyle and, for endpoints with a private CA,
+// caBundle keys consumers need besides the credentials
+func connectionDetails(bucketName string, s3Params *s3client.S3ClientParams) map[string]string {
+	details := map[string]string{
+		"bucketName": bucketName,
+		"pathStyle":  strconv.FormatBool(s3Params.Profile.PathStyle),
+	}
+	if len(s3Params.TlsCert) > 0 {
+		details["caBundle"] = string(s3Params.TlsCert)
+	}
+	return details
+}
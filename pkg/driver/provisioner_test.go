@@ -0,0 +1,238 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+	bucketfake "sigs.k8s.io/container-object-storage-interface-api/client/clientset/versioned/fake"
+	bucketinformers "sigs.k8s.io/container-object-storage-interface-api/client/informers/externalversions"
+	cosispec "sigs.k8s.io/container-object-storage-interface-spec"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/k8s"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+// Account Secret of the fake backend used by tests
+const (
+	testAccountSecret    = "account1"
+	testAccountNamespace = "cosi"
+)
+
+// testAccountParameters are class parameters referencing the account Secret of the fake backend
+func testAccountParameters() map[string]string {
+	return map[string]string{
+		"accountSecret":          testAccountSecret,
+		"accountSecretNamespace": testAccountNamespace,
+	}
+}
+
+// newTestProvisioner returns a provisioner server whose account Secret points at backend. The
+// Kubernetes and COSI objects are served by fake clientsets, the latter through synced informers.
+func newTestProvisioner(t *testing.T, backend *s3clienttest.Server, provider string, objects ...runtime.Object) *provisionerServer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	account := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: testAccountSecret, Namespace: testAccountNamespace},
+		Data:       backend.SecretData(provider),
+	}
+	kubeObjects := []runtime.Object{account}
+	var bucketObjects []runtime.Object
+	for _, obj := range objects {
+		switch obj.(type) {
+		case *objectstoragev1alpha1.Bucket, *objectstoragev1alpha1.BucketClaim, *objectstoragev1alpha1.BucketAccess,
+			*objectstoragev1alpha1.BucketClass, *objectstoragev1alpha1.BucketAccessClass:
+			bucketObjects = append(bucketObjects, obj)
+		default:
+			kubeObjects = append(kubeObjects, obj)
+		}
+	}
+	clientset := fake.NewSimpleClientset(kubeObjects...)
+	bucketClientset := bucketfake.NewSimpleClientset(bucketObjects...)
+
+	bucketInformers := bucketinformers.NewSharedInformerFactory(bucketClientset, 0)
+	bucketAccessIndex, err := k8s.NewBucketAccessIndex(bucketInformers, bucketClientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucketIndex, err := k8s.NewBucketIndex(bucketInformers, bucketClientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &provisionerServer{
+		Provisioner:             config.DriverName,
+		Clientset:               clientset,
+		BucketClientset:         bucketClientset,
+		ClientCache:             s3client.NewClientCache(ctx, clientset, false, nil),
+		BucketAccessIndex:       bucketAccessIndex,
+		BucketIndex:             bucketIndex,
+		BucketLister:            bucketInformers.Objectstorage().V1alpha1().Buckets().Lister(),
+		BucketClaimLister:       bucketInformers.Objectstorage().V1alpha1().BucketClaims().Lister(),
+		BucketAccessClassLister: bucketInformers.Objectstorage().V1alpha1().BucketAccessClasses().Lister(),
+		CredentialsSecretLister: newCredentialsSecretLister(ctx, clientset),
+		Recorder:                record.NewFakeRecorder(100),
+	}
+	bucketInformers.Start(ctx.Done())
+	for typ, synced := range bucketInformers.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			t.Fatalf("informer for %v did not sync", typ)
+		}
+	}
+	return s
+}
+
+// expectEvent fails the test unless the fake recorder of s holds an Event with reason
+func expectEvent(t *testing.T, s *provisionerServer, reason string) {
+	t.Helper()
+	events := s.Recorder.(*record.FakeRecorder).Events
+	for {
+		select {
+		case event := <-events:
+			if strings.Contains(event, " "+reason+" ") {
+				return
+			}
+		default:
+			t.Errorf("no %s event was recorded", reason)
+			return
+		}
+	}
+}
+
+func TestCreateBucketNameTaken(t *testing.T) {
+	bucket := &objectstoragev1alpha1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "bc-1"},
+		Spec: objectstoragev1alpha1.BucketSpec{
+			DriverName:  config.DriverName,
+			BucketClaim: &corev1.ObjectReference{Name: "data", Namespace: "team-a"},
+		},
+	}
+	parameters := testAccountParameters()
+	parameters[config.BucketNamePatternKey] = "${namespace}-${claimName}"
+
+	backend := s3clienttest.NewServer(t)
+	backend.AddForeignBucket("team-a-data")
+	s := newTestProvisioner(t, backend, s3client.ProviderMinIO, bucket)
+
+	_, err := s.DriverCreateBucket(context.Background(), &cosispec.DriverCreateBucketRequest{Name: "bc-1", Parameters: parameters})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("DriverCreateBucket = %v, want AlreadyExists", err)
+	}
+	expectEvent(t, s, ReasonBucketCreateFailed)
+
+	// With ${hash} the claim gets a name of its own
+	parameters[config.BucketNamePatternKey] = "${namespace}-${claimName}-${hash}"
+	resp, err := s.DriverCreateBucket(context.Background(), &cosispec.DriverCreateBucketRequest{Name: "bc-1", Parameters: parameters})
+	if err != nil {
+		t.Fatalf("DriverCreateBucket: %v", err)
+	}
+	if _, ok := backend.Bucket(resp.BucketId); !ok || !strings.HasPrefix(resp.BucketId, "team-a-data-") {
+		t.Errorf("bucket %q was not created", resp.BucketId)
+	}
+}
+
+func TestDryRunLeavesBackendUntouched(t *testing.T) {
+	parameters := testAccountParameters()
+	parameters[config.QuotaBytesKey] = "1073741824"
+	bucket := &objectstoragev1alpha1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "bc-1"},
+		Spec: objectstoragev1alpha1.BucketSpec{
+			DriverName:  config.DriverName,
+			BucketClaim: &corev1.ObjectReference{Name: "data", Namespace: "team-a"},
+			Parameters:  parameters,
+		},
+		Status: objectstoragev1alpha1.BucketStatus{BucketID: "bc-1"},
+	}
+	claim := &objectstoragev1alpha1.BucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "team-a"},
+		Status:     objectstoragev1alpha1.BucketClaimStatus{BucketName: "bc-1"},
+	}
+	objects := []runtime.Object{bucket, claim}
+	for _, authType := range []objectstoragev1alpha1.AuthenticationType{
+		objectstoragev1alpha1.AuthenticationTypeKey, objectstoragev1alpha1.AuthenticationTypeIAM,
+	} {
+		name := strings.ToLower(string(authType))
+		objects = append(objects,
+			&objectstoragev1alpha1.BucketAccessClass{
+				ObjectMeta:         metav1.ObjectMeta{Name: name},
+				DriverName:         config.DriverName,
+				AuthenticationType: authType,
+				Parameters:         testAccountParameters(),
+			},
+			&objectstoragev1alpha1.BucketAccess{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", UID: types.UID(name)},
+				Spec:       objectstoragev1alpha1.BucketAccessSpec{BucketClaimName: "data", BucketAccessClassName: name},
+			})
+	}
+
+	backend := s3clienttest.NewServer(t)
+	s := newTestProvisioner(t, backend, s3client.ProviderMinIO, objects...)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.ClientCache = s3client.NewClientCache(ctx, s.Clientset, true, nil)
+
+	created, err := s.DriverCreateBucket(ctx, &cosispec.DriverCreateBucketRequest{Name: "bc-1", Parameters: parameters})
+	if err != nil {
+		t.Fatalf("DriverCreateBucket: %v", err)
+	}
+	keyGrant, err := s.DriverGrantBucketAccess(ctx, &cosispec.DriverGrantBucketAccessRequest{
+		BucketId: created.BucketId, Name: "key", AuthenticationType: cosispec.AuthenticationType_Key, Parameters: testAccountParameters(),
+	})
+	if err != nil {
+		t.Fatalf("DriverGrantBucketAccess with key: %v", err)
+	}
+	if got := keyGrant.Credentials["s3"].Secrets["accessKeyID"]; got == "" {
+		t.Error("key grant returned no access key")
+	}
+	iamGrant, err := s.DriverGrantBucketAccess(ctx, &cosispec.DriverGrantBucketAccessRequest{
+		BucketId: created.BucketId, Name: "iam", AuthenticationType: cosispec.AuthenticationType_IAM, Parameters: testAccountParameters(),
+	})
+	if err != nil {
+		t.Fatalf("DriverGrantBucketAccess with IAM: %v", err)
+	}
+	for _, accountID := range []string{keyGrant.AccountId, iamGrant.AccountId} {
+		if _, err := s.DriverRevokeBucketAccess(ctx, &cosispec.DriverRevokeBucketAccessRequest{BucketId: created.BucketId, AccountId: accountID}); err != nil {
+			t.Fatalf("DriverRevokeBucketAccess %s: %v", accountID, err)
+		}
+	}
+	if _, err := s.DriverDeleteBucket(ctx, &cosispec.DriverDeleteBucketRequest{BucketId: created.BucketId}); err != nil {
+		t.Fatalf("DriverDeleteBucket: %v", err)
+	}
+
+	// Only reads reached the backend; the admin quota call would show up as a PUT, i.e. s3:CreateBucket
+	for _, request := range backend.Requests() {
+		switch request {
+		case "s3:CreateBucket", "s3:DeleteBucket", "s3:PutBucketPolicy", "s3:DeleteBucketPolicy", "s3:PutBucketTagging",
+			"iam:CreateUser", "iam:DeleteUser", "iam:CreateAccessKey", "iam:DeleteAccessKey", "iam:TagUser",
+			"iam:CreateRole", "iam:PutRolePolicy", "iam:DeleteRolePolicy", "iam:DeleteRole", "sts:AssumeRole":
+			t.Errorf("dry run sent %s to the backend", request)
+		}
+	}
+	if _, ok := backend.Bucket(created.BucketId); ok {
+		t.Error("dry run created the bucket")
+	}
+	if _, ok := backend.User(keyGrant.AccountId); ok {
+		t.Error("dry run created the IAM user")
+	}
+	if _, ok := backend.Role(iamGrant.AccountId); ok {
+		t.Error("dry run created the IAM role")
+	}
+}
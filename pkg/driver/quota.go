@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/tenant"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+// tenantQuotas returns the quotas of the tenant namespace belongs to, if tenants are configured
+func (s *provisionerServer) tenantQuotas(namespace string) (*tenant.Tenant, s3client.Quotas) {
+	if s.Tenants == nil {
+		return nil, s3client.Quotas{}
+	}
+	t := s.Tenants.TenantOf(namespace)
+	if t == nil {
+		return nil, s3client.Quotas{}
+	}
+	return t, t.Quotas
+}
+
+// quotaExceeded records and returns the ResourceExhausted error for a reached quota
+func (s *provisionerServer) quotaExceeded(target runtime.Object, format string, args ...interface{}) error {
+	err := status.Errorf(codes.ResourceExhausted, format, args...)
+	klog.InfoS("Refused request over quota", "reason", status.Convert(err).Message())
+	s.recordEvent(target, corev1.EventTypeWarning, ReasonQuotaExceeded, "%s", status.Convert(err).Message())
+	return err
+}
+
+// checkBucketQuota refuses to provision bucketName when the account or the tenant of namespace
+// already has as many buckets as its quota allows. Buckets are counted from the Bucket CRs.
+func (s *provisionerServer) checkBucketQuota(target runtime.Object, bucketName, namespace string,
+	parameters map[string]string, s3Params *s3client.S3ClientParams) error {
+	t, tenantQuotas := s.tenantQuotas(namespace)
+	if s3Params.Quotas.MaxBuckets == 0 && tenantQuotas.MaxBuckets == 0 {
+		return nil
+	}
+
+	accountKey, err := s.ClientCache.AccountKey(parameters)
+	if err != nil {
+		return err
+	}
+	buckets, err := s.BucketLister.List(labels.Everything())
+	if err != nil {
+		return status.Error(codes.Internal, "failed to list buckets")
+	}
+
+	accountBuckets, tenantBuckets := 0, 0
+	for _, bucket := range buckets {
+		if bucket.Name == bucketName || bucket.DeletionTimestamp != nil || bucket.Spec.DriverName != s.Provisioner {
+			continue
+		}
+		if key, err := s.ClientCache.AccountKey(bucket.Spec.Parameters); err == nil && key == accountKey {
+			accountBuckets++
+		}
+		if t != nil && t.IncludesNamespace(claimNamespace(bucketClaimRef(bucket))) {
+			tenantBuckets++
+		}
+	}
+
+	if limit := s3Params.Quotas.MaxBuckets; limit > 0 && accountBuckets >= limit {
+		return s.quotaExceeded(target, "account %s has reached its quota of %d buckets", accountKey, limit)
+	}
+	if limit := tenantQuotas.MaxBuckets; limit > 0 && tenantBuckets >= limit {
+		return s.quotaExceeded(target, "tenant %s has reached its quota of %d buckets", t.Name, limit)
+	}
+	return nil
+}
+
+// checkGrantQuota refuses to grant bucketAccess when the account or the tenant of its namespace has
+// as many IAM users or granted BucketAccesses as its quotas allow, or when the bucket has as many
+// granted BucketAccesses as allowed. Both are counted from the BucketAccess CRs: every granted KEY
+// authenticated BucketAccess owns one IAM user.
+func (s *provisionerServer) checkGrantQuota(parameters map[string]string, s3Params *s3client.S3ClientParams,
+	bucketAccess *objectstoragev1alpha1.BucketAccess, createsUser bool) error {
+	t, tenantQuotas := s.tenantQuotas(bucketAccess.Namespace)
+	quotas := s3Params.Quotas
+
+	grantsLimit := quotas.MaxGrantsPerBucket
+	if tenantQuotas.MaxGrantsPerBucket > 0 && (grantsLimit == 0 || tenantQuotas.MaxGrantsPerBucket < grantsLimit) {
+		grantsLimit = tenantQuotas.MaxGrantsPerBucket
+	}
+	if grantsLimit == 0 && quotas.MaxUsers == 0 && quotas.MaxGrants == 0 &&
+		tenantQuotas.MaxUsers == 0 && tenantQuotas.MaxGrants == 0 {
+		return nil
+	}
+
+	accountKey := ""
+	if quotas.MaxUsers > 0 || quotas.MaxGrants > 0 {
+		key, err := s.ClientCache.AccountKey(parameters)
+		if err != nil {
+			return err
+		}
+		accountKey = key
+	}
+
+	bucketName := s.bucketOf(bucketAccess)
+	var accountUsers, accountGrants, bucketGrants, tenantUsers, tenantGrants int
+	for _, other := range s.BucketAccessIndex.List() {
+		if other.UID == bucketAccess.UID || !other.Status.AccessGranted || other.DeletionTimestamp != nil {
+			continue
+		}
+		class, err := s.BucketAccessClassLister.Get(other.Spec.BucketAccessClassName)
+		if err != nil || class.DriverName != s.Provisioner {
+			continue
+		}
+		ownsUser := strings.HasPrefix(other.Status.AccountID, s3client.UserNamePrefix)
+		if accountKey != "" {
+			if key, err := s.ClientCache.AccountKey(class.Parameters); err == nil && key == accountKey {
+				accountGrants++
+				if ownsUser {
+					accountUsers++
+				}
+			}
+		}
+		if bucketName != "" && s.bucketOf(other) == bucketName {
+			bucketGrants++
+		}
+		if t != nil && t.IncludesNamespace(other.Namespace) {
+			tenantGrants++
+			if ownsUser {
+				tenantUsers++
+			}
+		}
+	}
+
+	if limit := quotas.MaxUsers; limit > 0 && createsUser && accountUsers >= limit {
+		return s.quotaExceeded(bucketAccess, "account %s has reached its quota of %d IAM users", accountKey, limit)
+	}
+	if limit := quotas.MaxGrants; limit > 0 && accountGrants >= limit {
+		return s.quotaExceeded(bucketAccess, "account %s has reached its quota of %d granted accesses", accountKey, limit)
+	}
+	if grantsLimit > 0 && bucketGrants >= grantsLimit {
+		return s.quotaExceeded(bucketAccess, "bucket %s has reached its quota of %d granted accesses", bucketName, grantsLimit)
+	}
+	if limit := tenantQuotas.MaxUsers; limit > 0 && createsUser && tenantUsers >= limit {
+		return s.quotaExceeded(bucketAccess, "tenant %s has reached its quota of %d IAM users", t.Name, limit)
+	}
+	if limit := tenantQuotas.MaxGrants; limit > 0 && tenantGrants >= limit {
+		return s.quotaExceeded(bucketAccess, "tenant %s has reached its quota of %d granted accesses", t.Name, limit)
+	}
+	return nil
+}
+
+// bucketOf returns the name of the Bucket CR the BucketClaim of bucketAccess is bound to
+func (s *provisionerServer) bucketOf(bucketAccess *objectstoragev1alpha1.BucketAccess) string {
+	claim, err := s.BucketClaimLister.BucketClaims(bucketAccess.Namespace).Get(bucketAccess.Spec.BucketClaimName)
+	if err != nil {
+		return ""
+	}
+	return claim.Status.BucketName
+}
+
+// setBucketQuota applies the storage quota of the BucketClass to bucketName. Failures are returned as
+// Internal so that the sidecar retries, which reapplies the quota to the already owned bucket.
+func (s *provisionerServer) setBucketQuota(ctx context.Context, target runtime.Object, s3Client *s3client.S3Client,
+	bucketName string, quota s3client.BucketQuota) error {
+	if quota.IsZero() {
+		return nil
+	}
+	if err := s3Client.SetBucketQuota(ctx, bucketName, quota); err != nil {
+		klog.ErrorS(err, "Failed to set bucket quota", "bucketName", bucketName)
+		s.recordEvent(target, corev1.EventTypeWarning, ReasonBucketQuotaFailed, "Failed to set quota on bucket %s: %v", bucketName, err)
+		return status.Error(codes.Internal, "Failed to set bucket quota")
+	}
+	klog.InfoS("Successfully set bucket quota", "bucketName", bucketName)
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestCheckGrantQuota(t *testing.T) {
+	keyClass := &objectstoragev1alpha1.BucketAccessClass{
+		ObjectMeta:         metav1.ObjectMeta{Name: "key-bac"},
+		DriverName:         config.DriverName,
+		AuthenticationType: objectstoragev1alpha1.AuthenticationTypeKey,
+		Parameters:         testAccountParameters(),
+	}
+	iamClass := keyClass.DeepCopy()
+	iamClass.Name = "iam-bac"
+	iamClass.AuthenticationType = objectstoragev1alpha1.AuthenticationTypeIAM
+	granted := func(i int, class, accountID string) *objectstoragev1alpha1.BucketAccess {
+		return &objectstoragev1alpha1.BucketAccess{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("ba-%d", i), Namespace: "team-a", UID: types.UID(fmt.Sprint(i))},
+			Spec:       objectstoragev1alpha1.BucketAccessSpec{BucketAccessClassName: class},
+			Status:     objectstoragev1alpha1.BucketAccessStatus{AccessGranted: true, AccountID: accountID},
+		}
+	}
+	// Two granted accesses with IAM users and one with a role
+	objects := []runtime.Object{keyClass, iamClass,
+		granted(1, "key-bac", s3client.UserNamePrefix+"ba-1"),
+		granted(2, "key-bac", s3client.UserNamePrefix+"ba-2"),
+		granted(3, "iam-bac", s3client.RoleNamePrefix+"ba-3"),
+	}
+	request := granted(4, "key-bac", "")
+	request.Status = objectstoragev1alpha1.BucketAccessStatus{}
+
+	tests := []struct {
+		name        string
+		quotas      s3client.Quotas
+		createsUser bool
+		want        codes.Code
+	}{
+		{name: "no quotas", createsUser: true, want: codes.OK},
+		{name: "users below quota", quotas: s3client.Quotas{MaxUsers: 3}, createsUser: true, want: codes.OK},
+		{name: "users at quota", quotas: s3client.Quotas{MaxUsers: 2}, createsUser: true, want: codes.ResourceExhausted},
+		{name: "users at quota for a role", quotas: s3client.Quotas{MaxUsers: 2}, createsUser: false, want: codes.OK},
+		{name: "grants below quota", quotas: s3client.Quotas{MaxGrants: 4}, createsUser: true, want: codes.OK},
+		{name: "grants at quota", quotas: s3client.Quotas{MaxGrants: 3}, createsUser: false, want: codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := s3clienttest.NewServer(t)
+			s := newTestProvisioner(t, backend, s3client.ProviderMinIO, objects...)
+
+			err := s.checkGrantQuota(testAccountParameters(), &s3client.S3ClientParams{Quotas: tt.quotas}, request, tt.createsUser)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("checkGrantQuota = %v, want %v", err, tt.want)
+			}
+			if n := backend.Count("iam:ListUsers"); n != 0 {
+				t.Errorf("quota check listed IAM users %d times", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+const (
+	// credentialRefreshInterval is how often credentials Secrets are checked for upcoming expiry
+	credentialRefreshInterval = time.Minute
+	// bucketInfoKey is the key of the credentials Secret written by the COSI sidecar
+	bucketInfoKey = "BucketInfo"
+)
+
+// runCredentialRefresher keeps the credentials Secrets of BucketAccesses up to date: every Secret
+// gets the bucket name, addressing style and CA bundle the sidecar drops, temporary credentials of
+// IAM authenticated BucketAccesses get the session token the sidecar drops and are renewed by
+// assuming their role again before the session expires, web identity BucketAccesses get the role
+// and token file the sidecar drops, and the additional credential formats are rendered next to BucketInfo
+func (s *provisionerServer) runCredentialRefresher(ctx context.Context) {
+	wait.UntilWithContext(ctx, s.refreshCredentialsSecrets, credentialRefreshInterval)
+}
+
+func (s *provisionerServer) refreshCredentialsSecrets(ctx context.Context) {
+	for _, bucketAccess := range s.BucketAccessIndex.List() {
+		if !bucketAccess.Status.AccessGranted || bucketAccess.Spec.CredentialsSecretName == "" {
+			continue
+		}
+
+		bucketAccessClass, err := s.BucketAccessClassLister.Get(bucketAccess.Spec.BucketAccessClassName)
+		if err != nil {
+			klog.V(3).InfoS("skipping credential refresh, bucket access class not found",
+				"bucketAccess", bucketAccess.Name,
+				"namespace", bucketAccess.Namespace)
+			continue
+		}
+		if bucketAccessClass.DriverName != s.Provisioner {
+			continue
+		}
+
+		if err := s.refreshCredentialsSecret(ctx, bucketAccess, bucketAccessClass); err != nil {
+			klog.ErrorS(err, "failed to refresh credentials secret",
+				"bucketAccess", bucketAccess.Name,
+				"namespace", bucketAccess.Namespace)
+		}
+	}
+}
+
+// usesWebIdentityRole reports whether a BucketAccess was granted a role that pods assume with their
+// projected ServiceAccount token
+func usesWebIdentityRole(bucketAccess *objectstoragev1alpha1.BucketAccess, bucketAccessClass *objectstoragev1alpha1.BucketAccessClass) bool {
+	return bucketAccessClass.AuthenticationType == objectstoragev1alpha1.AuthenticationTypeIAM &&
+		strings.HasPrefix(bucketAccess.Status.AccountID, s3client.RoleNamePrefix) &&
+		usesWebIdentity(bucketAccessClass.Parameters)
+}
+
+// usesSessionCredentials reports whether a BucketAccess was granted temporary credentials that expire
+func usesSessionCredentials(bucketAccess *objectstoragev1alpha1.BucketAccess, bucketAccessClass *objectstoragev1alpha1.BucketAccessClass) bool {
+	return bucketAccessClass.AuthenticationType == objectstoragev1alpha1.AuthenticationTypeIAM &&
+		strings.HasPrefix(bucketAccess.Status.AccountID, s3client.RoleNamePrefix) &&
+		!usesWebIdentity(bucketAccessClass.Parameters)
+}
+
+func (s *provisionerServer) refreshCredentialsSecret(ctx context.Context,
+	bucketAccess *objectstoragev1alpha1.BucketAccess, bucketAccessClass *objectstoragev1alpha1.BucketAccessClass) error {
+	sessionCredentials := usesSessionCredentials(bucketAccess, bucketAccessClass)
+	webIdentity := usesWebIdentityRole(bucketAccess, bucketAccessClass)
+	formats, err := config.GetCredentialFormats(bucketAccessClass.Parameters)
+	if err != nil {
+		return err
+	}
+
+	secret, err := s.getCredentialsSecret(ctx, bucketAccess)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if secret.Labels[config.CredentialsSecretLabel] != "true" {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[config.CredentialsSecretLabel] = "true"
+		changed = true
+	}
+	written, err := s.writeConnectionDetails(ctx, bucketAccess, bucketAccessClass, secret)
+	if err != nil {
+		return err
+	}
+	changed = changed || written
+	if sessionCredentials {
+		refreshed, err := s.refreshSessionCredentials(ctx, bucketAccess, bucketAccessClass, secret)
+		if err != nil {
+			return err
+		}
+		changed = changed || refreshed
+	}
+	if webIdentity {
+		written, err := s.writeWebIdentityConfig(ctx, bucketAccess, bucketAccessClass, secret)
+		if err != nil {
+			return err
+		}
+		changed = changed || written
+	}
+	if len(formats) > 0 {
+		secretS3, err := readBucketInfo(secret)
+		if err != nil {
+			return err
+		}
+		// BucketInfo holds the endpoint and region the sidecar hardcodes, not those of the backend
+		_, s3Params, err := s.ClientCache.GetClient(ctx, bucketAccessClass.Parameters)
+		if err != nil {
+			return err
+		}
+		secretS3["endpoint"] = s3Params.GetFullEndpoint()
+		secretS3["region"] = s3Params.Region
+		for key, value := range renderCredentialFormats(secretS3, formats) {
+			if string(secret.Data[key]) != value {
+				secret.Data[key] = []byte(value)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	_, err = s.Clientset.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+// newCredentialsSecretLister starts an informer for the credentials Secrets the driver maintains.
+// Only Secrets carrying the credentials label are cached, not every Secret of the cluster.
+func newCredentialsSecretLister(ctx context.Context, clientset kubernetes.Interface) corev1listers.SecretLister {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = config.CredentialsSecretLabel + "=true"
+		}))
+	lister := factory.Core().V1().Secrets().Lister()
+	factory.Start(ctx.Done())
+	return lister
+}
+
+// getCredentialsSecret returns a copy of the credentials Secret of bucketAccess. Secrets the
+// informer does not hold yet, which the driver has not labeled, are read from the API server.
+func (s *provisionerServer) getCredentialsSecret(ctx context.Context, bucketAccess *objectstoragev1alpha1.BucketAccess) (*corev1.Secret, error) {
+	secret, err := s.CredentialsSecretLister.Secrets(bucketAccess.Namespace).Get(bucketAccess.Spec.CredentialsSecretName)
+	if err == nil {
+		return secret.DeepCopy(), nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	return s.Clientset.CoreV1().Secrets(bucketAccess.Namespace).Get(ctx, bucketAccess.Spec.CredentialsSecretName, metav1.GetOptions{})
+}
+
+// writeConnectionDetails writes the bucket name, addressing style and CA bundle of the grant into
+// secret, as the sidecar drops them from the grant response. Nothing is written until the Bucket
+// of the BucketAccess is known.
+func (s *provisionerServer) writeConnectionDetails(ctx context.Context, bucketAccess *objectstoragev1alpha1.BucketAccess,
+	bucketAccessClass *objectstoragev1alpha1.BucketAccessClass, secret *corev1.Secret) (bool, error) {
+	bucketName := s.bucketIDOf(bucketAccess)
+	if bucketName == "" {
+		return false, nil
+	}
+	_, s3Params, err := s.ClientCache.GetClient(ctx, bucketAccessClass.Parameters)
+	if err != nil {
+		return false, err
+	}
+
+	details := connectionDetails(bucketName, s3Params)
+	secretS3, err := readBucketInfo(secret)
+	if err == nil && equalDetails(secretS3, details) {
+		return false, nil
+	}
+	if err := updateBucketInfo(secret, details); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// equalDetails reports whether secretS3 already holds every key of details
+func equalDetails(secretS3, details map[string]string) bool {
+	for k, v := range details {
+		if secretS3[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// refreshSessionCredentials assumes the role of the BucketAccess again and writes the new
+// credentials into secret when the current ones are about to expire
+func (s *provisionerServer) refreshSessionCredentials(ctx context.Context, bucketAccess *objectstoragev1alpha1.BucketAccess,
+	bucketAccessClass *objectstoragev1alpha1.BucketAccessClass, secret *corev1.Secret) (bool, error) {
+	duration, err := config.GetSessionDuration(bucketAccessClass.Parameters)
+	if err != nil {
+		return false, err
+	}
+	if !sessionRefreshDue(secret, duration, time.Now()) {
+		return false, nil
+	}
+
+	s3Client, s3Params, err := s.ClientCache.GetClient(ctx, bucketAccessClass.Parameters)
+	if err != nil {
+		return false, err
+	}
+
+	name := bucketAccess.Status.AccountID
+	role, err := s3Client.IAM.GetRole(name)
+	if err != nil {
+		return false, err
+	}
+	creds, err := s3Client.AssumeBucketRole(aws.StringValue(role.Role.Arn), strings.TrimPrefix(name, s3client.RoleNamePrefix), duration)
+	if err != nil {
+		return false, err
+	}
+
+	secretS3 := fetchSessionCredentials(creds, s3Params.GetFullEndpoint(), "")["s3"].Secrets
+	if err := updateBucketInfo(secret, secretS3); err != nil {
+		return false, err
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[config.CredentialsExpiryKey] = secretS3["expiration"]
+
+	klog.InfoS("Refreshed temporary credentials",
+		"bucketAccess", bucketAccess.Name,
+		"namespace", bucketAccess.Namespace,
+		"expiration", secretS3["expiration"])
+	s.recordEvent(bucketAccess, corev1.EventTypeNormal, ReasonCredentialsRefreshed,
+		"Refreshed temporary credentials, valid until %s", secretS3["expiration"])
+	return true, nil
+}
+
+// writeWebIdentityConfig writes the role, token file and STS endpoint that pods need for
+// AssumeRoleWithWebIdentity into secret, as the sidecar drops them from the grant response
+func (s *provisionerServer) writeWebIdentityConfig(ctx context.Context, bucketAccess *objectstoragev1alpha1.BucketAccess,
+	bucketAccessClass *objectstoragev1alpha1.BucketAccessClass, secret *corev1.Secret) (bool, error) {
+	tokenFile := bucketAccessClass.Parameters[config.WebIdentityTokenFileKey]
+	if secretS3, err := readBucketInfo(secret); err == nil &&
+		secretS3["roleArn"] != "" && secretS3["webIdentityTokenFile"] == tokenFile {
+		return false, nil
+	}
+
+	s3Client, s3Params, err := s.ClientCache.GetClient(ctx, bucketAccessClass.Parameters)
+	if err != nil {
+		return false, err
+	}
+	role, err := s3Client.IAM.GetRole(bucketAccess.Status.AccountID)
+	if err != nil {
+		return false, err
+	}
+	if err := updateBucketInfo(secret, map[string]string{
+		"roleArn":              aws.StringValue(role.Role.Arn),
+		"webIdentityTokenFile": tokenFile,
+		"stsEndpoint":          s3Params.GetFullSTSEndpoint(),
+	}); err != nil {
+		return false, err
+	}
+
+	klog.InfoS("Wrote web identity configuration to credentials secret",
+		"bucketAccess", bucketAccess.Name,
+		"namespace", bucketAccess.Namespace,
+		"roleArn", aws.StringValue(role.Role.Arn))
+	return true, nil
+}
+
+// sessionRefreshDue reports whether the temporary credentials in secret must be renewed. The
+// sidecar only copies the access key and secret key of a grant into the Secret, so a Secret
+// without a session token is unusable and renewed right away; otherwise the credentials are
+// renewed in the last quarter of their session.
+func sessionRefreshDue(secret *corev1.Secret, duration time.Duration, now time.Time) bool {
+	secretS3, err := readBucketInfo(secret)
+	if err != nil || secretS3["sessionToken"] == "" {
+		return true
+	}
+
+	// Secrets written by the sidecar carry no expiry; their session started when they were created
+	expiry := secret.CreationTimestamp.Add(duration)
+	if value, ok := secret.Annotations[config.CredentialsExpiryKey]; ok {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			expiry = parsed
+		}
+	}
+	return expiry.Sub(now) <= duration/4
+}
+
+// readBucketInfo returns the S3 credentials from the BucketInfo document of a credentials Secret
+func readBucketInfo(secret *corev1.Secret) (map[string]string, error) {
+	var bucketInfo struct {
+		Spec struct {
+			SecretS3 map[string]interface{} `json:"secretS3"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(secret.Data[bucketInfoKey], &bucketInfo); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", bucketInfoKey, err)
+	}
+	secretS3 := map[string]string{}
+	for k, v := range bucketInfo.Spec.SecretS3 {
+		if value, ok := v.(string); ok {
+			secretS3[k] = value
+		}
+	}
+	return secretS3, nil
+}
+
+// updateBucketInfo replaces the S3 credentials in the BucketInfo document of a credentials Secret,
+// preserving any fields the driver does not know about
+func updateBucketInfo(secret *corev1.Secret, secretS3 map[string]string) error {
+	bucketInfo := map[string]interface{}{}
+	if raw, ok := secret.Data[bucketInfoKey]; ok {
+		if err := json.Unmarshal(raw, &bucketInfo); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", bucketInfoKey, err)
+		}
+	}
+
+	spec, _ := bucketInfo["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = map[string]interface{}{}
+		bucketInfo["spec"] = spec
+	}
+	s3, _ := spec["secretS3"].(map[string]interface{})
+	if s3 == nil {
+		s3 = map[string]interface{}{}
+		spec["secretS3"] = s3
+	}
+	for k, v := range secretS3 {
+		s3[k] = v
+	}
+
+	raw, err := json.Marshal(bucketInfo)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[bucketInfoKey] = raw
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestRefreshRendersFormatsForBackend(t *testing.T) {
+	backend := s3clienttest.NewServer(t)
+
+	// The sidecar writes the endpoint and region it hardcodes into BucketInfo
+	secret := credentialsSecret(t, time.Now(), map[string]string{
+		"accessKeyID":     "AKIA1",
+		"accessSecretKey": "secret",
+		"endpoint":        "https://s3.amazonaws.com",
+		"region":          "us-west-1",
+	})
+	s := newTestProvisioner(t, backend, s3client.ProviderMinIO, secret)
+
+	bucketAccess := &objectstoragev1alpha1.BucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "ba", Namespace: "team-a", UID: "1"},
+		Spec:       objectstoragev1alpha1.BucketAccessSpec{CredentialsSecretName: "ba-creds"},
+		Status:     objectstoragev1alpha1.BucketAccessStatus{AccessGranted: true, AccountID: "cosi-user-ba-1"},
+	}
+	parameters := testAccountParameters()
+	parameters[config.CredentialFormatsKey] = "env,rclone"
+	bucketAccessClass := &objectstoragev1alpha1.BucketAccessClass{
+		DriverName:         config.DriverName,
+		AuthenticationType: objectstoragev1alpha1.AuthenticationTypeKey,
+		Parameters:         parameters,
+	}
+
+	if err := s.refreshCredentialsSecret(context.Background(), bucketAccess, bucketAccessClass); err != nil {
+		t.Fatalf("refreshCredentialsSecret: %v", err)
+	}
+	updated, err := s.Clientset.CoreV1().Secrets("team-a").Get(context.Background(), "ba-creds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(updated.Data["AWS_ENDPOINT_URL"]); got != backend.URL {
+		t.Errorf("AWS_ENDPOINT_URL = %q, want %q", got, backend.URL)
+	}
+	if got := string(updated.Data["AWS_REGION"]); got != "us-east-1" {
+		t.Errorf("AWS_REGION = %q, want the region of the account", got)
+	}
+	if rclone := string(updated.Data["rclone.conf"]); !strings.Contains(rclone, "endpoint = "+backend.URL) {
+		t.Errorf("rclone.conf does not use the backend endpoint:\n%s", rclone)
+	}
+	if updated.Labels[config.CredentialsSecretLabel] != "true" {
+		t.Error("credentials secret was not labeled for the informer")
+	}
+}
+
+func TestRefreshWritesConnectionDetails(t *testing.T) {
+	backend := s3clienttest.NewServer(t)
+
+	secret := credentialsSecret(t, time.Now(), map[string]string{
+		"accessKeyID":     "AKIA1",
+		"accessSecretKey": "secret",
+	})
+	claim := &objectstoragev1alpha1.BucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "team-a"},
+		Status:     objectstoragev1alpha1.BucketClaimStatus{BucketName: "bc-1"},
+	}
+	bucket := &objectstoragev1alpha1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "bc-1"},
+		Spec:       objectstoragev1alpha1.BucketSpec{DriverName: config.DriverName},
+		Status:     objectstoragev1alpha1.BucketStatus{BucketReady: true, BucketID: "team-a-data-1a2b3c4d"},
+	}
+	s := newTestProvisioner(t, backend, s3client.ProviderMinIO, secret, claim, bucket)
+
+	bucketAccess := &objectstoragev1alpha1.BucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "ba", Namespace: "team-a", UID: "1"},
+		Spec:       objectstoragev1alpha1.BucketAccessSpec{BucketClaimName: "data", CredentialsSecretName: "ba-creds"},
+		Status:     objectstoragev1alpha1.BucketAccessStatus{AccessGranted: true, AccountID: "cosi-user-ba-1"},
+	}
+	bucketAccessClass := &objectstoragev1alpha1.BucketAccessClass{
+		DriverName:         config.DriverName,
+		AuthenticationType: objectstoragev1alpha1.AuthenticationTypeKey,
+		Parameters:         testAccountParameters(),
+	}
+
+	if err := s.refreshCredentialsSecret(context.Background(), bucketAccess, bucketAccessClass); err != nil {
+		t.Fatalf("refreshCredentialsSecret: %v", err)
+	}
+	updated, err := s.Clientset.CoreV1().Secrets("team-a").Get(context.Background(), "ba-creds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretS3, err := readBucketInfo(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secretS3["bucketName"] != "team-a-data-1a2b3c4d" {
+		t.Errorf("bucketName = %q, want the backend bucket name", secretS3["bucketName"])
+	}
+	if secretS3["pathStyle"] != "true" {
+		t.Errorf("pathStyle = %q, want true for MinIO", secretS3["pathStyle"])
+	}
+	if secretS3["accessKeyID"] != "AKIA1" {
+		t.Errorf("accessKeyID = %q, the credentials written by the sidecar were lost", secretS3["accessKeyID"])
+	}
+
+	// A second pass finds the details in place and leaves the Secret alone
+	if written, err := s.writeConnectionDetails(context.Background(), bucketAccess, bucketAccessClass, updated); err != nil || written {
+		t.Errorf("writeConnectionDetails = %v, %v, want no change", written, err)
+	}
+}
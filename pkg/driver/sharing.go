@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"path"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+)
+
+// authorizeConsumer checks that the owner of the bucket consented to access from the namespace of
+// bucketAccess. Accesses from the namespace of the BucketClaim that provisioned the bucket are always
+// allowed; other namespaces must be listed in the allowed-consumer-namespaces annotation of that
+// BucketClaim, or of the Bucket when it was not provisioned for a claim.
+func (s *provisionerServer) authorizeConsumer(bucketAccess *objectstoragev1alpha1.BucketAccess) error {
+	claim, err := s.BucketClaimLister.BucketClaims(bucketAccess.Namespace).Get(bucketAccess.Spec.BucketClaimName)
+	if err != nil {
+		klog.ErrorS(err, "failed to get bucket claim",
+			"bucketClaim", bucketAccess.Spec.BucketClaimName,
+			"namespace", bucketAccess.Namespace)
+		return status.Error(codes.Internal, "failed to get bucket claim")
+	}
+	bucket, err := s.BucketLister.Get(claim.Status.BucketName)
+	if err != nil {
+		klog.ErrorS(err, "failed to get bucket", "bucket", claim.Status.BucketName)
+		return status.Error(codes.Internal, "failed to get bucket")
+	}
+
+	annotations := bucket.Annotations
+	owner := bucketClaimRef(bucket)
+	if owner != nil {
+		if owner.Namespace == bucketAccess.Namespace {
+			return nil
+		}
+		ownerClaim, err := s.BucketClaimLister.BucketClaims(owner.Namespace).Get(owner.Name)
+		if apierrors.IsNotFound(err) {
+			// Without its claim nobody can consent to sharing the bucket any more
+			klog.InfoS("Denied access to bucket whose owning claim was deleted",
+				"bucket", bucket.Name,
+				"bucketClaim", owner.Name,
+				"namespace", owner.Namespace)
+			s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonAccessDenied,
+				"Bucket %s is owned by BucketClaim %s/%s, which no longer exists",
+				bucket.Name, owner.Namespace, owner.Name)
+			return status.Errorf(codes.FailedPrecondition, "bucket claim %s/%s owning bucket %s was deleted",
+				owner.Namespace, owner.Name, bucket.Name)
+		}
+		if err != nil {
+			klog.ErrorS(err, "failed to get owning bucket claim", "bucketClaim", owner.Name, "namespace", owner.Namespace)
+			return status.Error(codes.Internal, "failed to get owning bucket claim")
+		}
+		annotations = ownerClaim.Annotations
+	}
+
+	if namespaceAllowed(annotations[config.AllowedConsumerNamespacesKey], bucketAccess.Namespace) {
+		return nil
+	}
+	klog.InfoS("Denied access to bucket shared without consent",
+		"bucket", bucket.Name,
+		"namespace", bucketAccess.Namespace)
+	s.recordEvent(bucketAccess, corev1.EventTypeWarning, ReasonAccessDenied,
+		"Bucket %s is not shared with namespace %s, its owner must list it in the %s annotation",
+		bucket.Name, bucketAccess.Namespace, config.AllowedConsumerNamespacesKey)
+	return status.Errorf(codes.PermissionDenied, "bucket %s is not shared with namespace %s", bucket.Name, bucketAccess.Namespace)
+}
+
+// namespaceAllowed reports whether namespace matches one of the comma separated names or patterns
+func namespaceAllowed(allowed, namespace string) bool {
+	for _, pattern := range strings.Split(allowed, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
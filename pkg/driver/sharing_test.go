@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestAuthorizeConsumer(t *testing.T) {
+	ownerClaim := &objectstoragev1alpha1.BucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "data",
+			Namespace:   "team-a",
+			Annotations: map[string]string{config.AllowedConsumerNamespacesKey: "team-b, analytics-*"},
+		},
+		Status: objectstoragev1alpha1.BucketClaimStatus{BucketName: "bc-1"},
+	}
+	bucket := &objectstoragev1alpha1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "bc-1"},
+		Spec: objectstoragev1alpha1.BucketSpec{
+			DriverName:  config.DriverName,
+			BucketClaim: &corev1.ObjectReference{Name: "data", Namespace: "team-a"},
+		},
+	}
+	// Claims of other namespaces bound to the same Bucket, as for statically shared buckets
+	consumerClaim := func(namespace string) *objectstoragev1alpha1.BucketClaim {
+		return &objectstoragev1alpha1.BucketClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: namespace},
+			Status:     objectstoragev1alpha1.BucketClaimStatus{BucketName: "bc-1"},
+		}
+	}
+	bucketAccess := func(namespace, claim string) *objectstoragev1alpha1.BucketAccess {
+		return &objectstoragev1alpha1.BucketAccess{
+			ObjectMeta: metav1.ObjectMeta{Name: "ba", Namespace: namespace},
+			Spec:       objectstoragev1alpha1.BucketAccessSpec{BucketClaimName: claim},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		bucketAccess *objectstoragev1alpha1.BucketAccess
+		deleteOwner  bool
+		want         codes.Code
+	}{
+		{name: "owner namespace", bucketAccess: bucketAccess("team-a", "data"), want: codes.OK},
+		{name: "listed namespace", bucketAccess: bucketAccess("team-b", "shared"), want: codes.OK},
+		{name: "matching pattern", bucketAccess: bucketAccess("analytics-eu", "shared"), want: codes.OK},
+		{name: "not listed", bucketAccess: bucketAccess("team-c", "shared"), want: codes.PermissionDenied},
+		{name: "owner claim deleted", bucketAccess: bucketAccess("team-b", "shared"), deleteOwner: true, want: codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{bucket, consumerClaim("team-b"), consumerClaim("team-c"), consumerClaim("analytics-eu")}
+			if !tt.deleteOwner {
+				objects = append(objects, ownerClaim)
+			}
+			s := newTestProvisioner(t, s3clienttest.NewServer(t), "", objects...)
+
+			err := s.authorizeConsumer(tt.bucketAccess)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("authorizeConsumer = %v, want %v", err, tt.want)
+			}
+			if tt.want != codes.OK {
+				expectEvent(t, s, ReasonAccessDenied)
+			}
+		})
+	}
+}
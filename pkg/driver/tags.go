@@ -0,0 +1,71 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/config"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+// setTag sets key to value unless value is empty
+func setTag(tags map[string]string, key, value string) {
+	if value != "" {
+		tags[key] = value
+	}
+}
+
+// bucketTags returns the provenance tags of a bucket created for the Bucket CR crName, merged over
+// the extra tags of the BucketClass
+func (s *provisionerServer) bucketTags(extra map[string]string, crName string, claimRef *corev1.ObjectReference) map[string]string {
+	tags := make(map[string]string, len(extra)+5)
+	for key, value := range extra {
+		tags[key] = value
+	}
+	setTag(tags, config.TagClusterID, s.ClusterID)
+	setTag(tags, config.TagBucket, crName)
+	if claimRef != nil {
+		setTag(tags, config.TagNamespace, claimRef.Namespace)
+		setTag(tags, config.TagBucketClaim, claimRef.Name)
+		setTag(tags, config.TagBucketClaimUID, string(claimRef.UID))
+	}
+	return tags
+}
+
+// userTags returns the provenance tags of the IAM user created for bucketAccess, merged over the
+// extra tags of the BucketAccessClass
+func (s *provisionerServer) userTags(extra map[string]string, bucketAccess *objectstoragev1alpha1.BucketAccess) map[string]string {
+	tags := make(map[string]string, len(extra)+4)
+	for key, value := range extra {
+		tags[key] = value
+	}
+	setTag(tags, config.TagClusterID, s.ClusterID)
+	setTag(tags, config.TagNamespace, bucketAccess.Namespace)
+	setTag(tags, config.TagBucketAccess, bucketAccess.Name)
+	setTag(tags, config.TagBucketAccessUID, string(bucketAccess.UID))
+	return tags
+}
+
+// tagBucket tags bucketName. Tags only serve attribution on the storage side, so failures, e.g. on
+// backends without bucket tagging, are logged rather than failing provisioning.
+func tagBucket(ctx context.Context, s3Client *s3client.S3Client, bucketName string, tags map[string]string) {
+	if err := s3Client.PutBucketTagging(ctx, bucketName, tags); err != nil {
+		klog.ErrorS(err, "Failed to tag bucket", "bucketName", bucketName)
+	}
+}
+
+// tagUser tags the IAM user userName, logging failures like tagBucket
+func tagUser(ctx context.Context, s3Client *s3client.S3Client, userName string, tags map[string]string) {
+	if err := s3Client.TagUser(ctx, userName, tags); err != nil {
+		klog.ErrorS(err, "Failed to tag IAM user", "userName", userName)
+	}
+}
@@ -0,0 +1,30 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package driver
+
+import (
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// authorizeTenant denies requests from namespace for an account that belongs to another tenant.
+// The denial is posted as an Event on target.
+func (s *provisionerServer) authorizeTenant(target runtime.Object, namespace string, parameters map[string]string) error {
+	if s.Tenants == nil {
+		return nil
+	}
+	accountKey, err := s.ClientCache.AccountKey(parameters)
+	if err != nil {
+		return err
+	}
+	if err := s.Tenants.Authorize(namespace, accountKey); err != nil {
+		s.recordEvent(target, corev1.EventTypeWarning, ReasonAccessDenied, "%s", status.Convert(err).Message())
+		return err
+	}
+	return nil
+}
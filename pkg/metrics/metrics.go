@@ -0,0 +1,148 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+const namespace = "s3_iam_cosi"
+
+// Label values used when no backend error code is available
+const (
+	// CodeOK is recorded for backend calls that completed without error
+	CodeOK = "OK"
+	// CodeUnknown is recorded for backend errors that carry no AWS error code
+	CodeUnknown = "Unknown"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "Total number of COSI provisioner RPCs handled, by method and gRPC result code.",
+	}, []string{"method", "code"})
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of COSI provisioner RPCs, by method and gRPC result code.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"method", "code"})
+
+	backendRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backend_requests_total",
+		Help:      "Total number of S3/IAM backend API calls, by service, operation, endpoint and error code.",
+	}, []string{"service", "operation", "endpoint", "code"})
+
+	backendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backend_request_duration_seconds",
+		Help:      "Latency of S3/IAM backend API calls including retries, by service, operation and endpoint.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"service", "operation", "endpoint"})
+
+	orphanedUsers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "orphaned_users",
+		Help:      "Number of IAM users without a BucketAccess found by the last garbage collection pass, by account.",
+	}, []string{"account"})
+
+	orphanedUsersDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orphaned_users_deleted_total",
+		Help:      "Total number of orphaned IAM users deleted by the garbage collector, by account.",
+	}, []string{"account"})
+
+	policyDrift = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "policy_drift_total",
+		Help:      "Total number of bucket policy statements found removed or altered and restored, by account.",
+	}, []string{"account"})
+)
+
+func init() {
+	registry.MustRegister(
+		rpcRequests,
+		rpcDuration,
+		backendRequests,
+		backendDuration,
+		orphanedUsers,
+		orphanedUsersDeleted,
+		policyDrift,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Registry returns the registry holding all driver metrics
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// ObserveRPC records the outcome and latency of a provisioner RPC started at start
+func ObserveRPC(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	rpcRequests.WithLabelValues(method, code).Inc()
+	rpcDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
+
+// ObserveBackendCall records the outcome and latency of a single S3 or IAM API call
+func ObserveBackendCall(service, operation, endpoint, code string, duration time.Duration) {
+	backendRequests.WithLabelValues(service, operation, endpoint, code).Inc()
+	backendDuration.WithLabelValues(service, operation, endpoint).Observe(duration.Seconds())
+}
+
+// ObserveOrphanedUsers records the orphaned IAM users found for account by a garbage collection pass
+func ObserveOrphanedUsers(account string, found, deleted int) {
+	orphanedUsers.WithLabelValues(account).Set(float64(found))
+	orphanedUsersDeleted.WithLabelValues(account).Add(float64(deleted))
+}
+
+// ObservePolicyDrift records the bucket policy statements of account restored by a drift detection pass
+func ObservePolicyDrift(account string, restored int) {
+	policyDrift.WithLabelValues(account).Add(float64(restored))
+}
+
+// Serve exposes the metrics endpoint on addr until ctx is cancelled
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.ErrorS(err, "failed to shut down metrics server")
+		}
+	}()
+
+	klog.InfoS("Starting metrics server", "address", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
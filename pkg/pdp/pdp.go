@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+// Package pdp consults an external policy decision point before access is granted
+package pdp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+)
+
+// requestTimeout bounds a single decision so that a slow decision point cannot stall grants
+const requestTimeout = 5 * time.Second
+
+// Input describes the grant the decision point rules on
+type Input struct {
+	BucketAccess      *objectstoragev1alpha1.BucketAccess      `json:"bucketAccess"`
+	BucketAccessClass *objectstoragev1alpha1.BucketAccessClass `json:"bucketAccessClass"`
+	// Bucket is the Bucket CR, when it could be found
+	Bucket *objectstoragev1alpha1.Bucket `json:"bucket,omitempty"`
+	// BucketName is the name of the bucket on the backend
+	BucketName string `json:"bucketName"`
+	// AuthenticationType is Key or IAM, as in the BucketAccessClass
+	AuthenticationType string `json:"authenticationType"`
+	// Actions are the S3 actions the access mode of the BucketAccessClass grants
+	Actions []string `json:"actions"`
+}
+
+// Decision is the answer of the decision point. Actions, when set, narrow the requested actions;
+// the driver denies decisions that grant actions beyond them.
+type Decision struct {
+	Allow   bool     `json:"allow"`
+	Reason  string   `json:"reason,omitempty"`
+	Actions []string `json:"actions,omitempty"`
+}
+
+// DecisionPoint rules on grants
+type DecisionPoint interface {
+	Decide(ctx context.Context, input *Input) (*Decision, error)
+}
+
+// Webhook is a decision point reached over HTTP. It speaks the OPA data API: the input is POSTed
+// as {"input": ...} and the decision read from {"result": ...}, where the result is either a
+// Decision or a plain boolean. This lets a Rego module served by OPA, or any service answering
+// the same documents, act as the decision point.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a decision point that POSTs to url, e.g. http://localhost:8181/v1/data/cosi/grant
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+type webhookRequest struct {
+	Input *Input `json:"input"`
+}
+
+type webhookResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+// Decide asks the webhook to rule on input
+func (w *Webhook) Decide(ctx context.Context, input *Input) (*Decision, error) {
+	body, err := json.Marshal(webhookRequest{Input: input})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("policy decision point returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return parseDecision(data)
+}
+
+// parseDecision reads the result of a webhook response. A missing result, which OPA returns when
+// the rule is undefined, denies.
+func parseDecision(data []byte) (*Decision, error) {
+	var response webhookResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("invalid policy decision: %w", err)
+	}
+	result := bytes.TrimSpace(response.Result)
+	if len(result) == 0 || bytes.Equal(result, []byte("null")) {
+		return &Decision{Allow: false, Reason: "policy decision point returned no result"}, nil
+	}
+
+	var allow bool
+	if err := json.Unmarshal(result, &allow); err == nil {
+		return &Decision{Allow: allow}, nil
+	}
+	decision := &Decision{}
+	if err := json.Unmarshal(result, decision); err != nil {
+		return nil, fmt.Errorf("invalid policy decision: %w", err)
+	}
+	for _, action := range decision.Actions {
+		if !strings.HasPrefix(action, "s3:") {
+			return nil, fmt.Errorf("invalid policy decision: %q is not an S3 action", action)
+		}
+	}
+	if decision.Allow && decision.Actions != nil && len(decision.Actions) == 0 {
+		return nil, fmt.Errorf("invalid policy decision: allowed with no actions")
+	}
+	klog.V(5).InfoS("Policy decision", "allow", decision.Allow, "reason", decision.Reason, "actions", decision.Actions)
+	return decision, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package pdp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDecision(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Decision
+		wantErr bool
+	}{
+		{name: "boolean allow", data: `{"result": true}`, want: &Decision{Allow: true}},
+		{name: "boolean deny", data: `{"result": false}`, want: &Decision{Allow: false}},
+		{name: "object", data: `{"result": {"allow": true, "actions": ["s3:GetObject"]}}`,
+			want: &Decision{Allow: true, Actions: []string{"s3:GetObject"}}},
+		{name: "object deny", data: `{"result": {"allow": false, "reason": "sandbox"}}`,
+			want: &Decision{Allow: false, Reason: "sandbox"}},
+		{name: "missing result", data: `{}`,
+			want: &Decision{Allow: false, Reason: "policy decision point returned no result"}},
+		{name: "null result", data: `{"result": null}`,
+			want: &Decision{Allow: false, Reason: "policy decision point returned no result"}},
+		{name: "not an S3 action", data: `{"result": {"allow": true, "actions": ["iam:CreateUser"]}}`, wantErr: true},
+		{name: "allowed with no actions", data: `{"result": {"allow": true, "actions": []}}`, wantErr: true},
+		{name: "invalid result", data: `{"result": "yes"}`, wantErr: true},
+		{name: "invalid JSON", data: `{"result": `, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDecision([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseDecision = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDecision: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDecision = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookDecide(t *testing.T) {
+	var received webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s with content type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &received); err != nil {
+			t.Errorf("request body is not an OPA input document: %q", data)
+		}
+		_, _ = w.Write([]byte(`{"result": {"allow": true, "actions": ["s3:GetObject"]}}`))
+	}))
+	defer server.Close()
+
+	input := &Input{BucketName: "bucket1", AuthenticationType: "Key", Actions: []string{"s3:GetObject", "s3:PutObject"}}
+	decision, err := NewWebhook(server.URL).Decide(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	if !decision.Allow || !reflect.DeepEqual(decision.Actions, []string{"s3:GetObject"}) {
+		t.Errorf("decision = %+v", decision)
+	}
+	if received.Input == nil || received.Input.BucketName != "bucket1" || !reflect.DeepEqual(received.Input.Actions, input.Actions) {
+		t.Errorf("webhook received input %+v", received.Input)
+	}
+}
+
+func TestWebhookDecideError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "policy not loaded", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := NewWebhook(server.URL).Decide(context.Background(), &Input{})
+	if err == nil || !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "policy not loaded") {
+		t.Errorf("Decide = %v, want the status and body of the response", err)
+	}
+
+	server.Close()
+	if _, err := NewWebhook(server.URL).Decide(context.Background(), &Input{}); err == nil {
+		t.Error("Decide succeeded against an unreachable decision point")
+	}
+}
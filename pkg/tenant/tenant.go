@@ -0,0 +1,231 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+// Package tenant enforces which Kubernetes namespaces may use which S3 accounts
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client"
+)
+
+// MappingKey is the ConfigMap key holding the tenant mapping
+const MappingKey = "tenants.yaml"
+
+// secretAccountPrefix is how the credential source of account Secrets is keyed
+const secretAccountPrefix = "secret:"
+
+// Mapping declares the tenants sharing the driver
+type Mapping struct {
+	Tenants []Tenant `json:"tenants"`
+	// AllowUnmappedAccounts lets any namespace use accounts that no tenant claims
+	AllowUnmappedAccounts bool `json:"allowUnmappedAccounts,omitempty"`
+}
+
+// Tenant binds a set of namespaces to the accounts they may use
+type Tenant struct {
+	Name string `json:"name"`
+	// Namespaces are namespace names or shell patterns such as "team-a-*"
+	Namespaces []string `json:"namespaces"`
+	// Accounts are account Secrets as "namespace/name", or credential sources as "file:<path>"
+	// and "http:<url>"
+	Accounts []string `json:"accounts"`
+	// Quotas limit what the driver provisions for the namespaces of the tenant
+	Quotas s3client.Quotas `json:"quotas,omitempty"`
+}
+
+// ParseMapping parses and validates a tenant mapping document
+func ParseMapping(data []byte) (*Mapping, error) {
+	mapping := &Mapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, err
+	}
+	for i, t := range mapping.Tenants {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tenant %d has no name", i)
+		}
+		for _, pattern := range t.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("tenant %s: invalid namespace pattern %q", t.Name, pattern)
+			}
+		}
+		for j, account := range t.Accounts {
+			mapping.Tenants[i].Accounts[j] = normalizeAccount(account)
+		}
+	}
+	return mapping, nil
+}
+
+// normalizeAccount turns the "namespace/name" shorthand for account Secrets into a credential source key
+func normalizeAccount(account string) string {
+	if strings.Contains(account, ":") {
+		return account
+	}
+	return secretAccountPrefix + account
+}
+
+// Authorize returns PermissionDenied unless namespace may use the account identified by accountKey
+func (m *Mapping) Authorize(namespace, accountKey string) error {
+	mapped := false
+	for _, t := range m.Tenants {
+		if !t.ownsAccount(accountKey) {
+			continue
+		}
+		mapped = true
+		if t.IncludesNamespace(namespace) {
+			return nil
+		}
+	}
+
+	if !mapped && m.AllowUnmappedAccounts {
+		return nil
+	}
+	if namespace == "" {
+		return status.Errorf(codes.PermissionDenied, "requesting namespace unknown, account %s is restricted to its tenants", accountKey)
+	}
+	if !mapped {
+		return status.Errorf(codes.PermissionDenied, "account %s is not mapped to any tenant", accountKey)
+	}
+	return status.Errorf(codes.PermissionDenied, "namespace %s may not use account %s of another tenant", namespace, accountKey)
+}
+
+func (t *Tenant) ownsAccount(accountKey string) bool {
+	for _, account := range t.Accounts {
+		if account == accountKey {
+			return true
+		}
+	}
+	return false
+}
+
+// IncludesNamespace reports whether namespace belongs to the tenant
+func (t *Tenant) IncludesNamespace(namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	for _, pattern := range t.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforcer authorizes requests against the tenant mapping in a ConfigMap, reloading it on change.
+// It fails closed: while the ConfigMap is missing or invalid every request is denied.
+type Enforcer struct {
+	namespace string
+	name      string
+
+	mu      sync.RWMutex
+	mapping *Mapping
+	err     error
+}
+
+// NewEnforcer watches the ConfigMap namespace/name for the tenant mapping until ctx is done
+func NewEnforcer(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*Enforcer, error) {
+	e := &Enforcer{
+		namespace: namespace,
+		name:      name,
+		err:       fmt.Errorf("tenant mapping ConfigMap %s/%s not found", namespace, name),
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			e.load(obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			e.load(newObj)
+		},
+		DeleteFunc: func(interface{}) {
+			e.set(nil, fmt.Errorf("tenant mapping ConfigMap %s/%s was deleted", namespace, name))
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return nil, fmt.Errorf("failed to sync tenant mapping ConfigMap %s/%s", namespace, name)
+	}
+	return e, nil
+}
+
+func (e *Enforcer) load(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	mapping, err := ParseMapping([]byte(configMap.Data[MappingKey]))
+	if err != nil {
+		klog.ErrorS(err, "Invalid tenant mapping, denying all requests", "configMap", e.namespace+"/"+e.name)
+		e.set(nil, fmt.Errorf("tenant mapping ConfigMap %s/%s is invalid: %w", e.namespace, e.name, err))
+		return
+	}
+	klog.InfoS("Loaded tenant mapping", "configMap", e.namespace+"/"+e.name, "tenants", len(mapping.Tenants))
+	e.set(mapping, nil)
+}
+
+func (e *Enforcer) set(mapping *Mapping, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.mapping = mapping
+	e.err = err
+}
+
+// Authorize returns PermissionDenied unless namespace may use the account identified by accountKey
+func (e *Enforcer) Authorize(namespace, accountKey string) error {
+	e.mu.RLock()
+	mapping, err := e.mapping, e.err
+	e.mu.RUnlock()
+
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err := mapping.Authorize(namespace, accountKey); err != nil {
+		klog.InfoS("Denied request by tenant mapping", "namespace", namespace, "account", accountKey)
+		return err
+	}
+	return nil
+}
+
+// TenantOf returns the tenant namespace belongs to, or nil if the mapping is not loaded or no
+// tenant includes the namespace
+func (e *Enforcer) TenantOf(namespace string) *Tenant {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.mapping == nil {
+		return nil
+	}
+	for i := range e.mapping.Tenants {
+		if e.mapping.Tenants[i].IncludesNamespace(namespace) {
+			return &e.mapping.Tenants[i]
+		}
+	}
+	return nil
+}
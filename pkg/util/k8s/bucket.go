@@ -0,0 +1,113 @@
+/*
+Copyright 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+	bucketclientset "sigs.k8s.io/container-object-storage-interface-api/client/clientset/versioned"
+	bucketinformers "sigs.k8s.io/container-object-storage-interface-api/client/informers/externalversions"
+)
+
+// bucketIDIndex is the informer index that maps the backend bucket name to the Bucket CR
+const bucketIDIndex = "bucketID"
+
+// BucketIndex looks up Bucket CRs by the bucket ID the driver returned for them, which is
+// the backend bucket name and differs from the CR name when a bucketNamePattern is used
+type BucketIndex struct {
+	bucketClientset bucketclientset.Interface
+	informer        cache.SharedIndexInformer
+}
+
+// NewBucketIndex registers a bucket ID indexed Bucket informer with the factory.
+// The factory must be started by the caller.
+func NewBucketIndex(factory bucketinformers.SharedInformerFactory, bucketClientset bucketclientset.Interface) (*BucketIndex, error) {
+	informer := factory.Objectstorage().V1alpha1().Buckets().Informer()
+	err := informer.AddIndexers(cache.Indexers{
+		bucketIDIndex: func(obj interface{}) ([]string, error) {
+			bucket, ok := obj.(*objectstoragev1alpha1.Bucket)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object type %T", obj)
+			}
+			if bucket.Status.BucketID == "" {
+				return nil, nil
+			}
+			return []string{bucket.Status.BucketID}, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BucketIndex{
+		bucketClientset: bucketClientset,
+		informer:        informer,
+	}, nil
+}
+
+// Find returns the Bucket CR whose bucket ID is bucketID. Buckets created without a
+// bucketNamePattern have the CR name as ID, so the CR of that name is used otherwise. Bucket IDs
+// the informer has not observed yet are reported as NotFound rather than listing every Bucket.
+func (i *BucketIndex) Find(ctx context.Context, bucketID string) (*objectstoragev1alpha1.Bucket, error) {
+	if !cache.WaitForCacheSync(ctx.Done(), i.informer.HasSynced) {
+		klog.ErrorS(ctx.Err(), "timed out waiting for bucket informer to sync")
+		return nil, status.Error(codes.Unavailable, "bucket informer has not synced")
+	}
+
+	if bucket, ok := i.Cached(bucketID); ok {
+		return bucket, nil
+	}
+
+	bucket, err := i.bucketClientset.ObjectstorageV1alpha1().Buckets().Get(ctx, bucketID, metav1.GetOptions{})
+	if err == nil {
+		return bucket, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		klog.ErrorS(err, "failed to get bucket", "bucketName", bucketID)
+		return nil, status.Error(codes.Internal, "failed to get bucket")
+	}
+
+	// The informer may not have observed the bucket ID the sidecar recorded yet; the sidecar retries
+	klog.V(3).InfoS("bucket not in informer cache", "bucketID", bucketID)
+	return nil, status.Error(codes.NotFound, "bucket not found")
+}
+
+// Cached returns the Bucket CR whose bucket ID is bucketID if the informer has it
+func (i *BucketIndex) Cached(bucketID string) (*objectstoragev1alpha1.Bucket, bool) {
+	objs, err := i.informer.GetIndexer().ByIndex(bucketIDIndex, bucketID)
+	if err != nil || len(objs) == 0 {
+		return nil, false
+	}
+	bucket, ok := objs[0].(*objectstoragev1alpha1.Bucket)
+	if !ok {
+		return nil, false
+	}
+	return bucket.DeepCopy(), true
+}
+
+// Owners returns the names of the Bucket CRs, other than name, whose bucket ID is bucketID
+func (i *BucketIndex) Owners(bucketID, name string) []string {
+	objs, err := i.informer.GetIndexer().ByIndex(bucketIDIndex, bucketID)
+	if err != nil {
+		return nil
+	}
+	var owners []string
+	for _, obj := range objs {
+		if bucket, ok := obj.(*objectstoragev1alpha1.Bucket); ok && bucket.Name != name {
+			owners = append(owners, bucket.Name)
+		}
+	}
+	return owners
+}
@@ -8,64 +8,147 @@ package k8s
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
 	bucketclientset "sigs.k8s.io/container-object-storage-interface-api/client/clientset/versioned"
+	bucketinformers "sigs.k8s.io/container-object-storage-interface-api/client/informers/externalversions"
+	bucketlisters "sigs.k8s.io/container-object-storage-interface-api/client/listers/objectstorage/v1alpha1"
 )
 
-// GetBucketAccessAndClass retrieves the BucketAccess and BucketAccessClass objects
-func GetBucketAccessAndClass(ctx context.Context, bucketClientset bucketclientset.Interface, bucketAccessId string) (*objectstoragev1alpha1.BucketAccess, *objectstoragev1alpha1.BucketAccessClass, error) {
-	bucketAccess, err := FindBucketAccess(ctx, bucketClientset, bucketAccessId)
+// bucketAccessUIDIndex is the informer index that maps a BucketAccess UID to the object
+const bucketAccessUIDIndex = "uid"
+
+// BucketAccessIndex looks up BucketAccess CRs by UID from a shared informer cache
+type BucketAccessIndex struct {
+	bucketClientset bucketclientset.Interface
+	informer        cache.SharedIndexInformer
+}
+
+// NewBucketAccessIndex registers a UID-indexed BucketAccess informer with the factory.
+// The factory must be started by the caller.
+func NewBucketAccessIndex(factory bucketinformers.SharedInformerFactory, bucketClientset bucketclientset.Interface) (*BucketAccessIndex, error) {
+	informer := factory.Objectstorage().V1alpha1().BucketAccesses().Informer()
+	err := informer.AddIndexers(cache.Indexers{
+		bucketAccessUIDIndex: func(obj interface{}) ([]string, error) {
+			ba, ok := obj.(*objectstoragev1alpha1.BucketAccess)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object type %T", obj)
+			}
+			return []string{string(ba.UID)}, nil
+		},
+	})
 	if err != nil {
-		klog.ErrorS(err, "failed to find bucket access", "bucketAccessId", bucketAccessId)
-		return nil, nil, err
+		return nil, err
 	}
 
-	bucketAccessClassName := bucketAccess.Spec.BucketAccessClassName
-	if bucketAccessClassName == "" {
-		klog.ErrorS(nil, "bucketAccessClassName is missing from BucketAccess CR")
-		return nil, nil, status.Error(codes.InvalidArgument, "bucketAccessClassName is required")
+	return &BucketAccessIndex{
+		bucketClientset: bucketClientset,
+		informer:        informer,
+	}, nil
+}
+
+// Find returns the BucketAccess with the UID encoded in bucketAccessId. A BucketAccess the informer
+// has not observed yet is read from the API server when hint locates it: with a name by a Get,
+// with only a namespace by a List of that namespace. Otherwise it is reported as NotFound and the
+// sidecar retries the call.
+func (i *BucketAccessIndex) Find(ctx context.Context, bucketAccessId string, hint types.NamespacedName) (*objectstoragev1alpha1.BucketAccess, error) {
+	if !cache.WaitForCacheSync(ctx.Done(), i.informer.HasSynced) {
+		klog.ErrorS(ctx.Err(), "timed out waiting for bucket access informer to sync")
+		return nil, status.Error(codes.Unavailable, "bucket access informer has not synced")
 	}
 
-	klog.InfoS("fetching bucket access class", "name", bucketAccessClassName)
-	bucketAccessClass, err := bucketClientset.ObjectstorageV1alpha1().BucketAccessClasses().Get(ctx, bucketAccessClassName, metav1.GetOptions{})
-	if err != nil {
-		klog.ErrorS(err, "failed to get bucket access class", "name", bucketAccessClassName)
-		return nil, nil, status.Error(codes.Internal, "failed to get bucket access class")
+	if ba, ok := i.Cached(bucketAccessId); ok {
+		return ba, nil
+	}
+
+	uid := types.UID(strings.TrimPrefix(bucketAccessId, "ba-"))
+	bucketAccesses := i.bucketClientset.ObjectstorageV1alpha1().BucketAccesses(hint.Namespace)
+	switch {
+	case hint.Namespace != "" && hint.Name != "":
+		ba, err := bucketAccesses.Get(ctx, hint.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.ErrorS(err, "failed to get bucket access", "namespace", hint.Namespace, "name", hint.Name)
+			return nil, status.Error(codes.Internal, "failed to get bucket access")
+		}
+		if err == nil && ba.UID == uid {
+			return ba, nil
+		}
+	case hint.Namespace != "":
+		list, err := bucketAccesses.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			klog.ErrorS(err, "failed to list bucket accesses", "namespace", hint.Namespace)
+			return nil, status.Error(codes.Internal, "failed to list bucket accesses")
+		}
+		for idx := range list.Items {
+			if list.Items[idx].UID == uid {
+				return &list.Items[idx], nil
+			}
+		}
 	}
 
-	return bucketAccess, bucketAccessClass, nil
+	klog.V(3).InfoS("bucket access not in informer cache", "uid", uid, "namespace", hint.Namespace)
+	return nil, status.Error(codes.NotFound, "bucket access not found")
 }
 
-// FindBucketAccess searches for a BucketAccess CR across all namespaces by its UID
-func FindBucketAccess(ctx context.Context, bucketClientset bucketclientset.Interface, bucketAccessId string) (*objectstoragev1alpha1.BucketAccess, error) {
-	// List BucketAccess CRs across all namespaces to find the one with matching UID
-	bucketAccessList, err := bucketClientset.ObjectstorageV1alpha1().BucketAccesses("").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		klog.ErrorS(err, "failed to list bucket accesses")
-		return nil, status.Error(codes.Internal, "failed to list bucket accesses")
+// Cached returns the BucketAccess with the UID encoded in bucketAccessId if the informer has it.
+// Unlike Find it never calls the API server, which suits lookups for objects that may be deleted.
+func (i *BucketAccessIndex) Cached(bucketAccessId string) (*objectstoragev1alpha1.BucketAccess, bool) {
+	objs, err := i.informer.GetIndexer().ByIndex(bucketAccessUIDIndex, strings.TrimPrefix(bucketAccessId, "ba-"))
+	if err != nil || len(objs) == 0 {
+		return nil, false
 	}
+	ba, ok := objs[0].(*objectstoragev1alpha1.BucketAccess)
+	if !ok {
+		return nil, false
+	}
+	return ba.DeepCopy(), true
+}
 
-	// Remove "ba-" prefix if it exists
-	searchId := strings.TrimPrefix(bucketAccessId, "ba-")
-
-	var bucketAccess *objectstoragev1alpha1.BucketAccess
-	for _, ba := range bucketAccessList.Items {
-		if string(ba.UID) == searchId {
-			bucketAccess = &ba
-			break
+// List returns all BucketAccesses known to the informer
+func (i *BucketAccessIndex) List() []*objectstoragev1alpha1.BucketAccess {
+	objs := i.informer.GetStore().List()
+	bucketAccesses := make([]*objectstoragev1alpha1.BucketAccess, 0, len(objs))
+	for _, obj := range objs {
+		if ba, ok := obj.(*objectstoragev1alpha1.BucketAccess); ok {
+			bucketAccesses = append(bucketAccesses, ba)
 		}
 	}
+	return bucketAccesses
+}
+
+// GetBucketAccessAndClass retrieves the BucketAccess and its BucketAccessClass. hint locates the
+// BucketAccess should the index not have it yet, see Find.
+func GetBucketAccessAndClass(ctx context.Context, index *BucketAccessIndex, classLister bucketlisters.BucketAccessClassLister,
+	bucketAccessId string, hint types.NamespacedName) (*objectstoragev1alpha1.BucketAccess, *objectstoragev1alpha1.BucketAccessClass, error) {
+	bucketAccess, err := index.Find(ctx, bucketAccessId, hint)
+	if err != nil {
+		klog.ErrorS(err, "failed to find bucket access", "bucketAccessId", bucketAccessId)
+		return nil, nil, err
+	}
 
-	if bucketAccess == nil {
-		klog.ErrorS(nil, "failed to find bucket access", "uid", searchId)
-		return nil, status.Error(codes.NotFound, "bucket access not found")
+	bucketAccessClassName := bucketAccess.Spec.BucketAccessClassName
+	if bucketAccessClassName == "" {
+		klog.ErrorS(nil, "bucketAccessClassName is missing from BucketAccess CR")
+		return nil, nil, status.Error(codes.InvalidArgument, "bucketAccessClassName is required")
+	}
+
+	bucketAccessClass, err := classLister.Get(bucketAccessClassName)
+	if err != nil {
+		klog.ErrorS(err, "failed to get bucket access class", "name", bucketAccessClassName)
+		if apierrors.IsNotFound(err) {
+			return nil, nil, status.Error(codes.NotFound, "bucket access class not found")
+		}
+		return nil, nil, status.Error(codes.Internal, "failed to get bucket access class")
 	}
 
-	return bucketAccess, nil
+	return bucketAccess, bucketAccessClass.DeepCopy(), nil
 }
@@ -0,0 +1,67 @@
+/*
+Copyright 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	objectstoragev1alpha1 "sigs.k8s.io/container-object-storage-interface-api/apis/objectstorage/v1alpha1"
+	bucketfake "sigs.k8s.io/container-object-storage-interface-api/client/clientset/versioned/fake"
+	bucketinformers "sigs.k8s.io/container-object-storage-interface-api/client/informers/externalversions"
+)
+
+func TestBucketAccessIndexFindFallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The API server has the BucketAccess, the informer has not observed it yet
+	bucketAccess := &objectstoragev1alpha1.BucketAccess{
+		ObjectMeta: metav1.ObjectMeta{Name: "ba1", Namespace: "team-a", UID: "uid-1"},
+		Spec:       objectstoragev1alpha1.BucketAccessSpec{BucketAccessClassName: "bac"},
+	}
+	factory := bucketinformers.NewSharedInformerFactory(bucketfake.NewSimpleClientset(), 0)
+	index, err := NewBucketAccessIndex(factory, bucketfake.NewSimpleClientset(bucketAccess))
+	if err != nil {
+		t.Fatal(err)
+	}
+	classLister := factory.Objectstorage().V1alpha1().BucketAccessClasses().Lister()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	tests := []struct {
+		name string
+		hint types.NamespacedName
+		want codes.Code
+	}{
+		{name: "get by name", hint: types.NamespacedName{Namespace: "team-a", Name: "ba1"}, want: codes.OK},
+		{name: "list of namespace", hint: types.NamespacedName{Namespace: "team-a"}, want: codes.OK},
+		{name: "other namespace", hint: types.NamespacedName{Namespace: "team-b"}, want: codes.NotFound},
+		{name: "name of another access", hint: types.NamespacedName{Namespace: "team-a", Name: "ba2"}, want: codes.NotFound},
+		{name: "no hint", want: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ba, err := index.Find(ctx, "ba-uid-1", tt.hint)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("Find = %v, want %v", err, tt.want)
+			}
+			if tt.want == codes.OK && ba.Name != "ba1" {
+				t.Errorf("Find returned %s/%s", ba.Namespace, ba.Name)
+			}
+		})
+	}
+
+	// The class is read from the lister, a class the lister does not know is NotFound
+	_, _, err = GetBucketAccessAndClass(ctx, index, classLister, "ba-uid-1", types.NamespacedName{Namespace: "team-a", Name: "ba1"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetBucketAccessAndClass = %v, want NotFound for an unknown class", err)
+	}
+}
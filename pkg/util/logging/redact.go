@@ -0,0 +1,232 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Redacted replaces sensitive values in log output
+const Redacted = "[REDACTED]"
+
+// minSecretLength avoids redacting short values that would mangle unrelated log text
+const minSecretLength = 8
+
+// maxSecrets bounds the registered secrets, so that rotations cannot grow the registry and the
+// cost of scrubbing every log line without limit. The oldest secrets are forgotten first.
+const maxSecrets = 256
+
+var (
+	// sensitiveKeys are structured logging keys and parameter names whose values are never logged.
+	// Keys are compared case-insensitively.
+	sensitiveKeys = map[string]bool{
+		"accesskey":       true,
+		"accesskeyid":     true,
+		"secretkey":       true,
+		"accesssecretkey": true,
+		"secretaccesskey": true,
+		"sessiontoken":    true,
+		"token":           true,
+		"password":        true,
+		"userid":          true,
+		"tlsclientkey":    true,
+	}
+
+	// policyKeys are structured logging keys holding bucket policy documents
+	policyKeys = map[string]bool{
+		"policy": true,
+	}
+
+	// httpSecretHeaders matches signed request headers dumped by the AWS SDK debug logger
+	httpSecretHeaders = regexp.MustCompile(`(?im)^((?:authorization|x-amz-security-token|x-amz-content-sha256):\s*).*$`)
+	// signedQueryParams matches presigned URL query parameters
+	signedQueryParams = regexp.MustCompile(`(?i)(X-Amz-(?:Credential|Signature|Security-Token)=)[^&\s]+`)
+
+	secretsMu sync.RWMutex
+	// secrets counts the registrations of each secret, secretOrder lists them oldest first
+	secrets     = map[string]int{}
+	secretOrder []string
+)
+
+// RegisterSecret marks a credential value so that it is scrubbed from every log line until it is
+// unregistered as often as it was registered
+func RegisterSecret(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, v := range values {
+		if len(v) < minSecretLength {
+			continue
+		}
+		if secrets[v] == 0 {
+			if len(secretOrder) >= maxSecrets {
+				delete(secrets, secretOrder[0])
+				secretOrder = secretOrder[1:]
+			}
+			secretOrder = append(secretOrder, v)
+		}
+		secrets[v]++
+	}
+}
+
+// UnregisterSecret releases a registration of credential values that are no longer in use
+func UnregisterSecret(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, v := range values {
+		if secrets[v] == 0 {
+			continue
+		}
+		secrets[v]--
+		if secrets[v] > 0 {
+			continue
+		}
+		delete(secrets, v)
+		for i, secret := range secretOrder {
+			if secret == v {
+				secretOrder = append(secretOrder[:i], secretOrder[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// IsSensitiveKey reports whether values logged under key must be redacted
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+// Scrub removes registered secrets and signed HTTP headers from free-form text
+func Scrub(s string) string {
+	secretsMu.RLock()
+	for secret := range secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	secretsMu.RUnlock()
+
+	s = httpSecretHeaders.ReplaceAllString(s, "${1}"+Redacted)
+	return signedQueryParams.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// RedactParameters returns a copy of class or secret parameters with sensitive values masked
+func RedactParameters(parameters map[string]string) map[string]string {
+	redacted := make(map[string]string, len(parameters))
+	for k, v := range parameters {
+		if IsSensitiveKey(k) {
+			redacted[k] = Redacted
+			continue
+		}
+		redacted[k] = Scrub(v)
+	}
+	return redacted
+}
+
+// RedactPolicy masks the principals of every statement in a bucket policy document.
+// Documents that cannot be parsed are replaced entirely.
+func RedactPolicy(policy string) string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return Redacted
+	}
+	if statements, ok := doc["Statement"].([]interface{}); ok {
+		for _, stmt := range statements {
+			if m, ok := stmt.(map[string]interface{}); ok {
+				if _, ok := m["Principal"]; ok {
+					m["Principal"] = Redacted
+				}
+			}
+		}
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return Redacted
+	}
+	return string(out)
+}
+
+// redactValue masks a single structured logging value according to its key
+func redactValue(key string, value interface{}) interface{} {
+	lower := strings.ToLower(key)
+	switch {
+	case sensitiveKeys[lower]:
+		return Redacted
+	case policyKeys[lower]:
+		return RedactPolicy(fmt.Sprint(value))
+	}
+
+	switch v := value.(type) {
+	case string:
+		return Scrub(v)
+	case map[string]string:
+		return RedactParameters(v)
+	case fmt.Stringer:
+		return Scrub(v.String())
+	case error:
+		return Scrub(v.Error())
+	}
+	return value
+}
+
+// Filter is a klog.LogFilter that redacts credentials and policy principals
+type Filter struct{}
+
+var _ klog.LogFilter = Filter{}
+
+// Filter scrubs the arguments of Info/Error style calls. Only arguments whose text contains a
+// secret are replaced by their scrubbed text, so klog formats all others as usual.
+func (Filter) Filter(args []interface{}) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		out[i] = arg
+		var text string
+		switch v := arg.(type) {
+		case string:
+			text = v
+		case error:
+			text = v.Error()
+		case fmt.Stringer:
+			text = v.String()
+		default:
+			continue
+		}
+		if scrubbed := Scrub(text); scrubbed != text {
+			out[i] = scrubbed
+		}
+	}
+	return out
+}
+
+// FilterF scrubs the formatted output of Infof/Errorf style calls
+func (Filter) FilterF(format string, args []interface{}) (string, []interface{}) {
+	return "%s", []interface{}{Scrub(fmt.Sprintf(format, args...))}
+}
+
+// FilterS redacts values of sensitive keys in InfoS/ErrorS style calls
+func (Filter) FilterS(msg string, keysAndValues []interface{}) (string, []interface{}) {
+	out := make([]interface{}, len(keysAndValues))
+	copy(out, keysAndValues)
+	for i := 0; i+1 < len(out); i += 2 {
+		key, ok := out[i].(string)
+		if !ok {
+			continue
+		}
+		out[i+1] = redactValue(key, out[i+1])
+	}
+	return Scrub(msg), out
+}
+
+// Install routes all klog output through the redacting filter
+func Install() {
+	klog.SetLogFilter(Filter{})
+}
@@ -0,0 +1,189 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// resetSecrets empties the registry for the test and restores it afterwards
+func resetSecrets(t *testing.T) {
+	t.Helper()
+	secretsMu.Lock()
+	saved, savedOrder := secrets, secretOrder
+	secrets, secretOrder = map[string]int{}, nil
+	secretsMu.Unlock()
+	t.Cleanup(func() {
+		secretsMu.Lock()
+		secrets, secretOrder = saved, savedOrder
+		secretsMu.Unlock()
+	})
+}
+
+func TestScrub(t *testing.T) {
+	resetSecrets(t)
+	RegisterSecret("wJalrXUtnFEMI/K7MDENG", "short")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "registered secret", in: "secret=wJalrXUtnFEMI/K7MDENG used", want: "secret=" + Redacted + " used"},
+		{name: "short values are not registered", in: "mode=short", want: "mode=short"},
+		{name: "authorization header", in: "GET / HTTP/1.1\nAuthorization: AWS4-HMAC-SHA256 Credential=AKIA",
+			want: "GET / HTTP/1.1\nAuthorization: " + Redacted},
+		{name: "presigned url", in: "https://s3/b/k?X-Amz-Signature=abcdef&x=1", want: "https://s3/b/k?X-Amz-Signature=" + Redacted + "&x=1"},
+		{name: "nothing to scrub", in: "created bucket data", want: "created bucket data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Scrub(tt.in); got != tt.want {
+				t.Errorf("Scrub(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnregisterSecret(t *testing.T) {
+	resetSecrets(t)
+	const secret = "shared-secret-value"
+
+	// Two clients built from the same account hold the secret
+	RegisterSecret(secret)
+	RegisterSecret(secret)
+	UnregisterSecret(secret)
+	if got := Scrub(secret); got != Redacted {
+		t.Errorf("secret still registered once was logged as %q", got)
+	}
+	UnregisterSecret(secret)
+	if got := Scrub(secret); got != secret {
+		t.Errorf("released secret is still scrubbed: %q", got)
+	}
+	if len(secretOrder) != 0 {
+		t.Errorf("registry holds %d secrets after release, want 0", len(secretOrder))
+	}
+}
+
+func TestRegisterSecretBound(t *testing.T) {
+	resetSecrets(t)
+	secret := func(i int) string { return fmt.Sprintf("rotated-secret-%04d", i) }
+	for i := 0; i <= maxSecrets; i++ {
+		RegisterSecret(secret(i))
+	}
+
+	if len(secrets) != maxSecrets || len(secretOrder) != maxSecrets {
+		t.Fatalf("registry holds %d/%d secrets, want %d", len(secrets), len(secretOrder), maxSecrets)
+	}
+	if got := Scrub(secret(0)); got != secret(0) {
+		t.Error("oldest secret was not evicted")
+	}
+	if got := Scrub(secret(maxSecrets)); got != Redacted {
+		t.Error("newest secret is not scrubbed")
+	}
+}
+
+func TestRedactPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{
+			name:   "principals masked",
+			policy: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::1:user/u"]},"Action":"s3:*"}]}`,
+			want:   `{"Statement":[{"Action":"s3:*","Effect":"Allow","Principal":"` + Redacted + `"}],"Version":"2012-10-17"}`,
+		},
+		{
+			name:   "statement without principal",
+			policy: `{"Statement":[{"Effect":"Deny","NotPrincipal":"x"}]}`,
+			want:   `{"Statement":[{"Effect":"Deny","NotPrincipal":"x"}]}`,
+		},
+		{name: "unparsable", policy: `{"Statement":`, want: Redacted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactPolicy(tt.policy); got != tt.want {
+				t.Errorf("RedactPolicy = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	resetSecrets(t)
+	const secret = "registered-secret"
+	RegisterSecret(secret)
+
+	plainErr := errors.New("bucket not found")
+	args := []interface{}{"key " + secret, 42, plainErr, errors.New("denied for " + secret), []string{"a"}}
+	out := Filter{}.Filter(args)
+
+	if out[0] != "key "+Redacted {
+		t.Errorf("string with a secret = %v", out[0])
+	}
+	if out[1] != 42 {
+		t.Errorf("int argument was rewritten to %#v", out[1])
+	}
+	if out[2] != plainErr {
+		t.Errorf("error without a secret was rewritten to %#v", out[2])
+	}
+	if out[3] != "denied for "+Redacted {
+		t.Errorf("error with a secret = %v", out[3])
+	}
+	if fmt.Sprint(out[4]) != "[a]" {
+		t.Errorf("slice argument was rewritten to %#v", out[4])
+	}
+	if args[0] != "key "+secret {
+		t.Error("Filter modified the arguments of the caller")
+	}
+}
+
+func TestFilterS(t *testing.T) {
+	resetSecrets(t)
+	const secret = "registered-secret"
+	RegisterSecret(secret)
+
+	msg, out := Filter{}.FilterS("using "+secret, []interface{}{
+		"accessKey", "AKIAEXAMPLE",
+		"SecretKey", "value",
+		"policy", `{"Statement":[{"Principal":"arn:aws:iam::1:user/u"}]}`,
+		"parameters", map[string]string{"SecretKey": "value", "endpoint": "https://s3"},
+		"bucketName", "data",
+		"count", 3,
+	})
+	if msg != "using "+Redacted {
+		t.Errorf("message = %q", msg)
+	}
+	want := map[string]string{
+		"accessKey":  Redacted,
+		"SecretKey":  Redacted,
+		"policy":     `{"Statement":[{"Principal":"` + Redacted + `"}]}`,
+		"parameters": fmt.Sprint(map[string]string{"SecretKey": Redacted, "endpoint": "https://s3"}),
+		"bucketName": "data",
+		"count":      "3",
+	}
+	for i := 0; i+1 < len(out); i += 2 {
+		key := out[i].(string)
+		if got := fmt.Sprint(out[i+1]); got != want[key] {
+			t.Errorf("%s = %s, want %s", key, got, want[key])
+		}
+	}
+}
+
+func TestFilterF(t *testing.T) {
+	resetSecrets(t)
+	RegisterSecret("registered-secret")
+
+	format, args := Filter{}.FilterF("key %s for %d users", []interface{}{"registered-secret", 2})
+	if got := fmt.Sprintf(format, args...); !strings.Contains(got, Redacted) || strings.Contains(got, "registered-secret") {
+		t.Errorf("formatted output = %q", got)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package logging
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"k8s.io/klog/v2"
+	cosispec "sigs.k8s.io/container-object-storage-interface-spec"
+)
+
+// RequestFields returns the fields of a COSI provisioner request that are safe to log,
+// as klog key/value pairs. Parameters are included with sensitive values masked.
+func RequestFields(req interface{}) []interface{} {
+	switch r := req.(type) {
+	case *cosispec.DriverCreateBucketRequest:
+		return []interface{}{
+			"name", r.GetName(),
+			"parameters", RedactParameters(r.GetParameters()),
+		}
+	case *cosispec.DriverDeleteBucketRequest:
+		return []interface{}{
+			"bucketId", r.GetBucketId(),
+		}
+	case *cosispec.DriverGrantBucketAccessRequest:
+		return []interface{}{
+			"bucketId", r.GetBucketId(),
+			"name", r.GetName(),
+			"authenticationType", r.GetAuthenticationType().String(),
+			"parameters", RedactParameters(r.GetParameters()),
+		}
+	case *cosispec.DriverRevokeBucketAccessRequest:
+		return []interface{}{
+			"bucketId", r.GetBucketId(),
+			"accountId", r.GetAccountId(),
+		}
+	}
+	return []interface{}{"type", fmt.Sprintf("%T", req)}
+}
+
+// AWSLogger returns an AWS SDK logger that writes scrubbed output to klog.
+// Signed headers and registered credentials are removed from request and response dumps.
+func AWSLogger() aws.Logger {
+	return aws.LoggerFunc(func(args ...interface{}) {
+		klog.Info(Scrub(fmt.Sprint(args...)))
+	})
+}
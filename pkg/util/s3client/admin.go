@@ -0,0 +1,160 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+)
+
+// BucketQuota limits the storage a bucket may use. Zero means unlimited.
+type BucketQuota struct {
+	MaxBytes   int64
+	MaxObjects int64
+}
+
+// IsZero reports whether the quota sets no limit
+func (q BucketQuota) IsZero() bool {
+	return q.MaxBytes == 0 && q.MaxObjects == 0
+}
+
+// AdminClientInterface is an interface for provider specific administrative operations
+// that have no S3 or IAM equivalent
+type AdminClientInterface interface {
+	// SetBucketQuota applies quota to the bucket
+	SetBucketQuota(ctx context.Context, bucketName string, quota BucketQuota) error
+}
+
+// NewAdminClient returns the admin client for the provider of the account, or nil if the
+// provider has no admin API the driver supports
+func NewAdminClient(params *S3ClientParams) (AdminClientInterface, error) {
+	var profile string
+	if params.Profile != nil {
+		profile = params.Profile.Name
+	}
+
+	endpoint := params.GetFullEndpoint()
+	client, err := newHTTPClient(params, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	signed := &signedClient{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		region:   params.Region,
+		signer:   v4.NewSigner(credentials.NewStaticCredentials(params.AccessKey, params.SecretKey, "")),
+	}
+
+	switch profile {
+	case ProviderCephRGW:
+		return &rgwAdminClient{signedClient: signed, uid: params.AccountName}, nil
+	case ProviderMinIO:
+		return &minioAdminClient{signedClient: signed}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// signedClient sends SigV4 signed requests to the admin API served on the S3 endpoint
+type signedClient struct {
+	client   *http.Client
+	endpoint string
+	region   string
+	signer   *v4.Signer
+}
+
+func (c *signedClient) do(ctx context.Context, method, path string, query url.Values, body []byte) error {
+	reqURL := c.endpoint + path + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := c.signer.Sign(req, bytes.NewReader(body), "s3", c.region, time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// rgwAdminClient uses the Ceph RGW admin ops API
+type rgwAdminClient struct {
+	*signedClient
+	uid string
+}
+
+func (c *rgwAdminClient) SetBucketQuota(ctx context.Context, bucketName string, quota BucketQuota) error {
+	maxSize, maxObjects := int64(-1), int64(-1)
+	if quota.MaxBytes > 0 {
+		maxSize = quota.MaxBytes
+	}
+	if quota.MaxObjects > 0 {
+		maxObjects = quota.MaxObjects
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"enabled":     !quota.IsZero(),
+		"max_size":    maxSize,
+		"max_objects": maxObjects,
+	})
+	if err != nil {
+		return err
+	}
+	query := url.Values{"quota": {""}, "uid": {c.uid}, "bucket": {bucketName}}
+	return c.do(ctx, http.MethodPut, "/admin/bucket", query, body)
+}
+
+// minioAdminClient uses the MinIO admin API, which only limits the size of a bucket
+type minioAdminClient struct {
+	*signedClient
+}
+
+func (c *minioAdminClient) SetBucketQuota(ctx context.Context, bucketName string, quota BucketQuota) error {
+	if quota.MaxObjects > 0 {
+		return fmt.Errorf("MinIO does not support object count quotas")
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"quota":     quota.MaxBytes,
+		"quotatype": "hard",
+	})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, "/minio/admin/v3/set-bucket-quota", url.Values{"bucket": {bucketName}}, body)
+}
+
+// SetBucketQuota applies quota to the bucket through the provider admin API, recording the mutation in the audit log
+func (s *S3Client) SetBucketQuota(ctx context.Context, bucketName string, quota BucketQuota) error {
+	if s.Admin == nil {
+		return fmt.Errorf("provider %q has no supported quota API", s.profile().Name)
+	}
+	klog.InfoS("Setting bucket quota", "bucketName", bucketName, "maxBytes", quota.MaxBytes, "maxObjects", quota.MaxObjects)
+	err := s.Admin.SetBucketQuota(ctx, bucketName, quota)
+	s.recordAudit(ctx, audit.Event{Action: audit.ActionSetBucketQuota, Bucket: bucketName}, err)
+	return err
+}
@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// adminRequest is a request received by the fake admin API
+type adminRequest struct {
+	method        string
+	path          string
+	query         url.Values
+	body          map[string]interface{}
+	authorization string
+}
+
+// newAdminServer starts a fake admin API that records the requests it receives and answers with code
+func newAdminServer(t *testing.T, code int) (*httptest.Server, *[]adminRequest) {
+	t.Helper()
+	var requests []adminRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		request := adminRequest{
+			method:        r.Method,
+			path:          r.URL.Path,
+			query:         r.URL.Query(),
+			authorization: r.Header.Get("Authorization"),
+		}
+		if err := json.Unmarshal(data, &request.body); err != nil {
+			t.Errorf("admin request body is not JSON: %q", data)
+		}
+		requests = append(requests, request)
+		w.WriteHeader(code)
+		if code != http.StatusOK {
+			_, _ = w.Write([]byte(`{"Code":"AccessDenied"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// newAdminTestClient returns the admin client of provider for an account served by server
+func newAdminTestClient(t *testing.T, server *httptest.Server, provider string) AdminClientInterface {
+	t.Helper()
+	params, err := FetchParameters(map[string][]byte{
+		"Endpoint":    []byte(server.URL),
+		"AccountName": []byte("account1"),
+		"AccessKey":   []byte("AKIAADMIN"),
+		"SecretKey":   []byte("admin-secret"),
+		"Region":      []byte("us-east-1"),
+		"Provider":    []byte(provider),
+	})
+	if err != nil {
+		t.Fatalf("FetchParameters: %v", err)
+	}
+	admin, err := NewAdminClient(params)
+	if err != nil {
+		t.Fatalf("NewAdminClient: %v", err)
+	}
+	if admin == nil {
+		t.Fatalf("no admin client for %s", provider)
+	}
+	return admin
+}
+
+// checkSigned fails the test unless request carries a SigV4 signature of the account for S3
+func checkSigned(t *testing.T, request adminRequest) {
+	t.Helper()
+	if !strings.HasPrefix(request.authorization, "AWS4-HMAC-SHA256 Credential=AKIAADMIN/") ||
+		!strings.Contains(request.authorization, "/us-east-1/s3/aws4_request") {
+		t.Errorf("request is not signed with the account credentials: %q", request.authorization)
+	}
+}
+
+func TestRGWSetBucketQuota(t *testing.T) {
+	server, requests := newAdminServer(t, http.StatusOK)
+	admin := newAdminTestClient(t, server, ProviderCephRGW)
+
+	if err := admin.SetBucketQuota(context.Background(), "bucket1", BucketQuota{MaxBytes: 1 << 30}); err != nil {
+		t.Fatalf("SetBucketQuota: %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("admin API received %d requests, want 1", len(*requests))
+	}
+	request := (*requests)[0]
+	if request.method != http.MethodPut || request.path != "/admin/bucket" {
+		t.Errorf("request = %s %s, want PUT /admin/bucket", request.method, request.path)
+	}
+	if _, ok := request.query["quota"]; !ok {
+		t.Error("request lacks the quota query parameter")
+	}
+	if request.query.Get("uid") != "account1" || request.query.Get("bucket") != "bucket1" {
+		t.Errorf("query = %v, want the account uid and bucket", request.query)
+	}
+	// Unset limits are sent as -1, which RGW treats as unlimited
+	want := map[string]interface{}{"enabled": true, "max_size": float64(1 << 30), "max_objects": float64(-1)}
+	for key, value := range want {
+		if request.body[key] != value {
+			t.Errorf("body[%s] = %v, want %v", key, request.body[key], value)
+		}
+	}
+	checkSigned(t, request)
+}
+
+func TestMinIOSetBucketQuota(t *testing.T) {
+	server, requests := newAdminServer(t, http.StatusOK)
+	admin := newAdminTestClient(t, server, ProviderMinIO)
+
+	if err := admin.SetBucketQuota(context.Background(), "bucket1", BucketQuota{MaxBytes: 1 << 30}); err != nil {
+		t.Fatalf("SetBucketQuota: %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("admin API received %d requests, want 1", len(*requests))
+	}
+	request := (*requests)[0]
+	if request.method != http.MethodPut || request.path != "/minio/admin/v3/set-bucket-quota" {
+		t.Errorf("request = %s %s, want PUT /minio/admin/v3/set-bucket-quota", request.method, request.path)
+	}
+	if request.query.Get("bucket") != "bucket1" {
+		t.Errorf("query = %v, want the bucket", request.query)
+	}
+	if request.body["quota"] != float64(1<<30) || request.body["quotatype"] != "hard" {
+		t.Errorf("body = %v, want a hard quota of 1 GiB", request.body)
+	}
+	checkSigned(t, request)
+
+	if err := admin.SetBucketQuota(context.Background(), "bucket1", BucketQuota{MaxObjects: 10}); err == nil {
+		t.Error("MinIO accepted an object count quota")
+	}
+	if len(*requests) != 1 {
+		t.Error("an unsupported quota was sent to the admin API")
+	}
+}
+
+func TestSetBucketQuotaRejected(t *testing.T) {
+	server, _ := newAdminServer(t, http.StatusForbidden)
+	admin := newAdminTestClient(t, server, ProviderCephRGW)
+
+	err := admin.SetBucketQuota(context.Background(), "bucket1", BucketQuota{MaxObjects: 10})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("SetBucketQuota = %v, want the 403 of the admin API", err)
+	}
+}
+
+func TestNoAdminClientForOtherProviders(t *testing.T) {
+	params, err := FetchParameters(map[string][]byte{
+		"Endpoint":    []byte("https://scale.example.com"),
+		"AccountName": []byte("account1"),
+		"AccessKey":   []byte("AKIA1"),
+		"SecretKey":   []byte("secret"),
+		"Provider":    []byte(ProviderIBMScale),
+	})
+	if err != nil {
+		t.Fatalf("FetchParameters: %v", err)
+	}
+	if admin, err := NewAdminClient(params); err != nil || admin != nil {
+		t.Errorf("NewAdminClient = %v, %v, want no client", admin, err)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+)
+
+// recordAudit writes an audit event for a mutation against this client's backend
+func (s *S3Client) recordAudit(ctx context.Context, event audit.Event, err error) {
+	event.Endpoint = s.Endpoint
+	event.DryRun = s.DryRun
+	audit.Record(ctx, event.Failure(err))
+}
+
+// recordPolicyAudit writes an audit event for a bucket policy mutation, including the statement diff
+func (s *S3Client) recordPolicyAudit(ctx context.Context, action, bucketName, userName, before, after string, err error) {
+	event := audit.Event{
+		Action:     action,
+		Bucket:     bucketName,
+		User:       userName,
+		PolicyDiff: audit.DiffPolicies(before, after),
+	}
+	if before != "" {
+		event.PolicyBefore = []byte(before)
+	}
+	if after != "" {
+		event.PolicyAfter = []byte(after)
+	}
+	s.recordAudit(ctx, event, err)
+}
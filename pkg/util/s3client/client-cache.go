@@ -0,0 +1,284 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/logging"
+)
+
+// ClientCache shares S3 clients between RPCs that use the same account credentials.
+// Clients are keyed by credential source (for Secrets, their namespace/name) and are rebuilt whenever
+// the source's version (for Secrets, the resourceVersion) changes, so sessions and connection pools
+// are reused until the credentials are rotated.
+// Secrets are read through informers that are started on first use and watch only the account
+// Secret they were started for, so no other Secrets are cached.
+type ClientCache struct {
+	ctx               context.Context
+	clientset         kubernetes.Interface
+	dryRun            bool
+	credentialSources []string
+
+	mu          sync.Mutex
+	listers     map[string]*secretLister
+	clients     map[string]*cachedClient
+	credentials map[string]*fetchedCredentials
+}
+
+type secretLister struct {
+	lister    corev1listers.SecretLister
+	hasSynced cache.InformerSynced
+	// stop stops the informer
+	stop context.CancelFunc
+}
+
+// fetchedCredentials is account data fetched from a source that is not watched
+type fetchedCredentials struct {
+	data    map[string][]byte
+	version string
+	fetched time.Time
+}
+
+type cachedClient struct {
+	version string
+	client  *S3Client
+	params  *S3ClientParams
+}
+
+// NewClientCache creates an empty client cache. Informers started by the cache stop when ctx is done.
+// With dryRun the cache hands out clients that log mutations instead of performing them.
+// credentialSources are the path and URL prefixes the file and http credential sources may read;
+// without any only Secrets can hold account credentials.
+func NewClientCache(ctx context.Context, clientset kubernetes.Interface, dryRun bool, credentialSources []string) *ClientCache {
+	return &ClientCache{
+		ctx:               ctx,
+		clientset:         clientset,
+		dryRun:            dryRun,
+		credentialSources: credentialSources,
+		listers:           map[string]*secretLister{},
+		clients:           map[string]*cachedClient{},
+		credentials:       map[string]*fetchedCredentials{},
+	}
+}
+
+// AccountKey identifies the account credentials referenced by parameters without fetching them
+func (c *ClientCache) AccountKey(parameters map[string]string) (string, error) {
+	provider, err := c.NewCredentialProvider(parameters)
+	if err != nil {
+		return "", err
+	}
+	return provider.Key(), nil
+}
+
+// GetClient returns the S3 client and parameters for the account credentials referenced by parameters
+func (c *ClientCache) GetClient(ctx context.Context, parameters map[string]string) (*S3Client, *S3ClientParams, error) {
+	provider, err := c.NewCredentialProvider(parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, version, err := provider.Fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := provider.Key()
+
+	if cached, ok := c.cachedClient(key, version); ok {
+		klog.V(5).InfoS("Reusing cached S3 client", "source", key, "version", version)
+		return cached.client, cached.params, nil
+	}
+
+	// The client is built without holding the lock, so that RPCs for other accounts are not
+	// blocked while the S3, IAM, STS and admin clients are created
+	s3Params, err := FetchParameters(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s3Client, err := NewS3Client(s3Params, false)
+	if err != nil {
+		logging.UnregisterSecret(s3Params.AccessKey, s3Params.SecretKey, string(s3Params.TlsClientKey))
+		klog.ErrorS(err, "Failed to create s3 client")
+		return nil, nil, status.Error(codes.Internal, "Failed to create s3 client")
+	}
+	if c.dryRun {
+		s3Client = NewDryRunS3Client(s3Client)
+	}
+
+	built := &cachedClient{
+		version: version,
+		client:  s3Client,
+		params:  s3Params,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.clients[key]; ok {
+		if cached.version == version {
+			// Another RPC built a client for the same credentials first
+			built.release()
+			return cached.client, cached.params, nil
+		}
+		cached.release()
+	}
+	klog.InfoS("Created S3 client for account credentials", "source", key, "version", version)
+	c.clients[key] = built
+	return s3Client, s3Params, nil
+}
+
+// cachedClient returns the client cached for key if it was built from the given version
+func (c *ClientCache) cachedClient(key, version string) (*cachedClient, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.clients[key]
+	if !ok || cached.version != version {
+		return nil, false
+	}
+	return cached, true
+}
+
+// getSecret reads the account Secret from its informer, starting it if necessary
+func (c *ClientCache) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	sl := c.secretLister(namespace, name)
+	if !cache.WaitForCacheSync(ctx.Done(), sl.hasSynced) {
+		klog.ErrorS(ctx.Err(), "Timed out waiting for secret informer to sync", "namespace", namespace)
+		return nil, status.Error(codes.Unavailable, "secret informer has not synced")
+	}
+
+	secret, err := sl.lister.Secrets(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.ErrorS(err, "Account secret not found", "namespace", namespace, "name", name)
+			return nil, status.Error(codes.NotFound, "Failed to get object store user secret")
+		}
+		klog.ErrorS(err, "Failed to get object store user secret")
+		return nil, status.Error(codes.Internal, "Failed to get object store user secret")
+	}
+	return secret, nil
+}
+
+func (c *ClientCache) secretLister(namespace, name string) *secretLister {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := secretCacheKey(namespace, name)
+	if sl, ok := c.listers[key]; ok {
+		return sl
+	}
+
+	klog.InfoS("Starting secret informer", "namespace", namespace, "name", name)
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	secrets := factory.Core().V1().Secrets()
+	_, err := secrets.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret, ok := oldObj.(*corev1.Secret)
+			if !ok {
+				return
+			}
+			newSecret, ok := newObj.(*corev1.Secret)
+			if !ok || oldSecret.ResourceVersion == newSecret.ResourceVersion {
+				return
+			}
+			c.invalidate(secretCacheKey(newSecret.Namespace, newSecret.Name))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if secret, ok := obj.(*corev1.Secret); ok {
+				c.invalidate(secretCacheKey(secret.Namespace, secret.Name))
+			}
+		},
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to register secret event handler", "namespace", namespace, "name", name)
+	}
+	ctx, stop := context.WithCancel(c.ctx)
+	factory.Start(ctx.Done())
+
+	sl := &secretLister{
+		lister:    secrets.Lister(),
+		hasSynced: secrets.Informer().HasSynced,
+		stop:      stop,
+	}
+	c.listers[key] = sl
+	return sl
+}
+
+// cachedCredentials returns the account data last fetched for key if it is younger than ttl
+func (c *ClientCache) cachedCredentials(key string, ttl time.Duration) (map[string][]byte, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fetched, ok := c.credentials[key]
+	if !ok || time.Since(fetched.fetched) >= ttl {
+		return nil, "", false
+	}
+	return fetched.data, fetched.version, true
+}
+
+// storeCredentials remembers the account data fetched for key
+func (c *ClientCache) storeCredentials(key string, data map[string][]byte, version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.credentials[key] = &fetchedCredentials{
+		data:    data,
+		version: version,
+		fetched: time.Now(),
+	}
+}
+
+// invalidate drops the cached client for the given credential source key
+func (c *ClientCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.clients[key]; ok {
+		klog.InfoS("Account credentials changed, dropping cached S3 client", "source", key)
+		cached.release()
+		delete(c.clients, key)
+	}
+	c.stopInformer(key)
+}
+
+// stopInformer stops the informer watching the account Secret of key, if any. It is started again when
+// the Secret is next used.
+func (c *ClientCache) stopInformer(key string) {
+	if sl, ok := c.listers[key]; ok {
+		klog.InfoS("Stopping secret informer", "source", key)
+		sl.stop()
+		delete(c.listers, key)
+	}
+}
+
+// release stops scrubbing the credentials of a client that is no longer used from the logs
+func (c *cachedClient) release() {
+	logging.UnregisterSecret(c.params.AccessKey, c.params.SecretKey, string(c.params.TlsClientKey))
+}
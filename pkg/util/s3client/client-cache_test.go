@@ -0,0 +1,71 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestGetClientSharesClientAndStopsInformer(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "account", Namespace: "cosi", ResourceVersion: "1"},
+		Data:       server.SecretData(ProviderCephRGW),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache := NewClientCache(ctx, clientset, false, nil)
+	parameters := map[string]string{"accountSecret": "account", "accountSecretNamespace": "cosi"}
+
+	// Concurrent RPCs for the same account end up with the same client
+	clients := make([]*S3Client, 8)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, _, err := cache.GetClient(ctx, parameters)
+			if err != nil {
+				t.Errorf("GetClient: %v", err)
+				return
+			}
+			clients[i] = client
+		}(i)
+	}
+	wg.Wait()
+	for _, client := range clients[1:] {
+		if client != clients[0] {
+			t.Fatal("concurrent GetClient calls returned different clients")
+		}
+	}
+
+	// Deleting the Secret drops the client and stops its informer
+	if err := clientset.CoreV1().Secrets("cosi").Delete(ctx, "account", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	key := secretCacheKey("cosi", "account")
+	err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		_, cached := cache.clients[key]
+		_, watched := cache.listers[key]
+		return !cached && !watched, nil
+	})
+	if err != nil {
+		t.Fatal("client and secret informer were not released after the Secret was deleted")
+	}
+}
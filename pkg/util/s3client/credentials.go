@@ -0,0 +1,317 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// BucketClass / BucketAccessClass parameters selecting where account credentials come from
+const (
+	// ParamCredentialSource selects the credential provider, one of the CredentialSource* values
+	ParamCredentialSource = "accountCredentialSource"
+	// ParamCredentialPath is the mounted file or directory read by the file provider
+	ParamCredentialPath = "accountCredentialPath"
+	// ParamCredentialURL is the endpoint queried by the http provider
+	ParamCredentialURL = "accountCredentialURL"
+	// ParamCredentialTokenPath is a token file (e.g. a projected ServiceAccount token) sent as a
+	// bearer token to the http provider
+	ParamCredentialTokenPath = "accountCredentialTokenPath"
+)
+
+// Supported credential sources
+const (
+	// CredentialSourceSecret reads the account from a Kubernetes Secret (the default)
+	CredentialSourceSecret = "secret"
+	// CredentialSourceFile reads the account from a mounted file or directory
+	CredentialSourceFile = "file"
+	// CredentialSourceHTTP reads the account from an external secrets HTTP endpoint
+	CredentialSourceHTTP = "http"
+)
+
+// maxCredentialResponseSize bounds the body read from an external secrets endpoint
+const maxCredentialResponseSize = 1 << 20
+
+// httpCredentialTTL is how long the response of an external secrets endpoint is reused, so that
+// RPCs do not each cost a round-trip. Rotated credentials are picked up once it expires.
+const httpCredentialTTL = time.Minute
+
+// CredentialProvider fetches the account data, keyed like an account Secret
+// (Endpoint, AccessKey, SecretKey, ...), from a credential source
+type CredentialProvider interface {
+	// Key identifies the credential source, and is used to cache clients built from it
+	Key() string
+	// Fetch returns the account data and a version that changes whenever the data changes
+	Fetch(ctx context.Context) (map[string][]byte, string, error)
+}
+
+// NewCredentialProvider returns the provider selected by the class parameters. The file and http
+// sources, and the token of the latter, may only read the paths and URLs allowed by the cache.
+func (c *ClientCache) NewCredentialProvider(parameters map[string]string) (CredentialProvider, error) {
+	switch source := parameters[ParamCredentialSource]; source {
+	case "", CredentialSourceSecret:
+		secretName, namespace, err := FetchSecretNameAndNamespace(parameters)
+		if err != nil {
+			return nil, err
+		}
+		return &secretProvider{cache: c, namespace: namespace, name: secretName}, nil
+	case CredentialSourceFile:
+		path := parameters[ParamCredentialPath]
+		if path == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is required for credential source %q", ParamCredentialPath, source)
+		}
+		path = filepath.Clean(path)
+		if !c.credentialSourceAllowed(path) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s %q is not an allowed account credential source", ParamCredentialPath, path)
+		}
+		return &fileProvider{path: path}, nil
+	case CredentialSourceHTTP:
+		url := parameters[ParamCredentialURL]
+		if url == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is required for credential source %q", ParamCredentialURL, source)
+		}
+		if !c.credentialSourceAllowed(url) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s %q is not an allowed account credential source", ParamCredentialURL, url)
+		}
+		// The token is sent to the endpoint, so it may only be read from an allowed path as well
+		tokenPath := parameters[ParamCredentialTokenPath]
+		if tokenPath != "" {
+			tokenPath = filepath.Clean(tokenPath)
+			if !c.credentialSourceAllowed(tokenPath) {
+				return nil, status.Errorf(codes.PermissionDenied, "%s %q is not an allowed account credential source", ParamCredentialTokenPath, tokenPath)
+			}
+		}
+		return &httpProvider{
+			cache:     c,
+			url:       url,
+			tokenPath: tokenPath,
+			client:    &http.Client{Timeout: HttpTimeOut},
+		}, nil
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported %s %q", ParamCredentialSource, source)
+	}
+}
+
+// secretProvider reads the account from a Kubernetes Secret through the cache's informers
+type secretProvider struct {
+	cache     *ClientCache
+	namespace string
+	name      string
+}
+
+func (p *secretProvider) Key() string {
+	return secretCacheKey(p.namespace, p.name)
+}
+
+func (p *secretProvider) Fetch(ctx context.Context) (map[string][]byte, string, error) {
+	secret, err := p.cache.getSecret(ctx, p.namespace, p.name)
+	if err != nil {
+		return nil, "", err
+	}
+	return secret.Data, secret.ResourceVersion, nil
+}
+
+func secretCacheKey(namespace, name string) string {
+	return CredentialSourceSecret + ":" + namespace + "/" + name
+}
+
+// fileProvider reads the account from a mounted path. A directory holds one file per key, which is
+// the layout of mounted Secrets and CSI secret store volumes; a regular file holds a JSON object.
+type fileProvider struct {
+	path string
+}
+
+func (p *fileProvider) Key() string {
+	return CredentialSourceFile + ":" + p.path
+}
+
+func (p *fileProvider) Fetch(ctx context.Context) (map[string][]byte, string, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		klog.ErrorS(err, "Failed to read account credential path", "path", p.path)
+		return nil, "", status.Error(codes.Internal, "Failed to read account credentials")
+	}
+
+	var data map[string][]byte
+	if info.IsDir() {
+		data, err = readCredentialDir(p.path)
+	} else {
+		var raw []byte
+		raw, err = os.ReadFile(p.path)
+		if err == nil {
+			data, err = parseCredentialJSON(raw)
+		}
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to read account credentials", "path", p.path)
+		return nil, "", status.Error(codes.Internal, "Failed to read account credentials")
+	}
+	return data, credentialVersion(data), nil
+}
+
+// readCredentialDir reads every regular file in dir, skipping the hidden entries kubelet uses for
+// atomic volume updates
+func readCredentialDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// Mounted Secret keys are symlinks into the hidden data directory
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		data[entry.Name()] = value
+	}
+	return data, nil
+}
+
+// httpProvider reads the account from an external secrets endpoint returning a JSON object.
+// Vault KV responses ({"data": {...}} or {"data": {"data": {...}}}) are unwrapped.
+type httpProvider struct {
+	cache     *ClientCache
+	url       string
+	tokenPath string
+	client    *http.Client
+}
+
+func (p *httpProvider) Key() string {
+	return CredentialSourceHTTP + ":" + p.url
+}
+
+func (p *httpProvider) Fetch(ctx context.Context) (map[string][]byte, string, error) {
+	if data, version, ok := p.cache.cachedCredentials(p.Key(), httpCredentialTTL); ok {
+		return data, version, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, "", status.Errorf(codes.InvalidArgument, "invalid %s: %v", ParamCredentialURL, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.tokenPath != "" {
+		token, err := os.ReadFile(p.tokenPath)
+		if err != nil {
+			klog.ErrorS(err, "Failed to read credential endpoint token", "path", p.tokenPath)
+			return nil, "", status.Error(codes.Internal, "Failed to read credential endpoint token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		klog.ErrorS(err, "Failed to query credential endpoint", "url", p.url)
+		return nil, "", status.Error(codes.Unavailable, "Failed to query credential endpoint")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCredentialResponseSize))
+	if err != nil {
+		return nil, "", status.Error(codes.Unavailable, "Failed to read credential endpoint response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		klog.ErrorS(nil, "Credential endpoint returned an error", "url", p.url, "status", resp.StatusCode)
+		return nil, "", status.Errorf(codes.Unavailable, "credential endpoint returned status %d", resp.StatusCode)
+	}
+
+	data, err := parseCredentialJSON(body)
+	if err != nil {
+		klog.ErrorS(err, "Failed to parse credential endpoint response", "url", p.url)
+		return nil, "", status.Error(codes.Internal, "Failed to parse credential endpoint response")
+	}
+	version := credentialVersion(data)
+	p.cache.storeCredentials(p.Key(), data, version)
+	return data, version, nil
+}
+
+// credentialSourceAllowed reports whether the path or URL of a credential source equals or lies
+// below one of the allowed credential sources
+func (c *ClientCache) credentialSourceAllowed(source string) bool {
+	if strings.Contains(source, "..") {
+		return false
+	}
+	for _, allowed := range c.credentialSources {
+		if source == allowed {
+			return true
+		}
+		if !strings.HasSuffix(allowed, "/") {
+			allowed += "/"
+		}
+		if strings.HasPrefix(source, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCredentialJSON decodes a flat JSON object of string values, unwrapping Vault KV envelopes
+func parseCredentialJSON(raw []byte) (map[string][]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	for i := 0; i < 2; i++ {
+		inner, ok := doc["data"].(map[string]interface{})
+		if !ok {
+			break
+		}
+		doc = inner
+	}
+
+	data := map[string][]byte{}
+	for k, v := range doc {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("value of %q is not a string", k)
+		}
+		data[k] = []byte(s)
+	}
+	return data, nil
+}
+
+// credentialVersion hashes account data so that changed credentials produce a new client
+func credentialVersion(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
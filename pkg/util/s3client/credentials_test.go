@@ -0,0 +1,155 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newCredentialStub serves a Vault KV v2 response for the account and counts the requests
+func newCredentialStub(t *testing.T, token string) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"data": {"Endpoint": "https://s3.example.com", "AccessKey": "AKIAEXAMPLE", "SecretKey": "secret"}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPProviderFetch(t *testing.T) {
+	server, requests := newCredentialStub(t, "token")
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewClientCache(context.Background(), nil, false, []string{server.URL + "/v1/secret", filepath.Dir(tokenPath)})
+	provider, err := cache.NewCredentialProvider(map[string]string{
+		ParamCredentialSource:    CredentialSourceHTTP,
+		ParamCredentialURL:       server.URL + "/v1/secret/data/account1",
+		ParamCredentialTokenPath: tokenPath,
+	})
+	if err != nil {
+		t.Fatalf("NewCredentialProvider: %v", err)
+	}
+
+	data, version, err := provider.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := string(data["AccessKey"]); got != "AKIAEXAMPLE" {
+		t.Errorf("AccessKey = %q, want the unwrapped Vault value", got)
+	}
+
+	// A second fetch within the TTL is answered from the cache
+	_, cachedVersion, err := provider.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("second Fetch: %v", err)
+	}
+	if cachedVersion != version {
+		t.Errorf("cached version = %q, want %q", cachedVersion, version)
+	}
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("endpoint queried %d times, want 1", n)
+	}
+}
+
+func TestHTTPProviderRejectedToken(t *testing.T) {
+	server, _ := newCredentialStub(t, "token")
+
+	cache := NewClientCache(context.Background(), nil, false, []string{server.URL})
+	provider, err := cache.NewCredentialProvider(map[string]string{
+		ParamCredentialSource: CredentialSourceHTTP,
+		ParamCredentialURL:    server.URL + "/v1/secret/data/account1",
+	})
+	if err != nil {
+		t.Fatalf("NewCredentialProvider: %v", err)
+	}
+	if _, _, err := provider.Fetch(context.Background()); status.Code(err) != codes.Unavailable {
+		t.Errorf("Fetch without token: got %v, want Unavailable", err)
+	}
+}
+
+func TestCredentialSourceAllowlist(t *testing.T) {
+	cache := NewClientCache(context.Background(), nil, false, []string{
+		"/var/run/secrets/accounts",
+		"https://vault.example.com/v1/secret/",
+	})
+
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		allowed    bool
+	}{
+		{
+			name:       "file below allowed directory",
+			parameters: map[string]string{ParamCredentialSource: CredentialSourceFile, ParamCredentialPath: "/var/run/secrets/accounts/account1"},
+			allowed:    true,
+		},
+		{
+			name:       "file escaping allowed directory",
+			parameters: map[string]string{ParamCredentialSource: CredentialSourceFile, ParamCredentialPath: "/var/run/secrets/accounts/../../../etc/passwd"},
+		},
+		{
+			name:       "file in sibling directory",
+			parameters: map[string]string{ParamCredentialSource: CredentialSourceFile, ParamCredentialPath: "/var/run/secrets/accounts-other/account1"},
+		},
+		{
+			name:       "url below allowed prefix",
+			parameters: map[string]string{ParamCredentialSource: CredentialSourceHTTP, ParamCredentialURL: "https://vault.example.com/v1/secret/data/account1"},
+			allowed:    true,
+		},
+		{
+			name:       "url on another host",
+			parameters: map[string]string{ParamCredentialSource: CredentialSourceHTTP, ParamCredentialURL: "https://vault.example.com.attacker.io/v1/secret/data/account1"},
+		},
+		{
+			name: "token below allowed directory",
+			parameters: map[string]string{ParamCredentialSource: CredentialSourceHTTP,
+				ParamCredentialURL:       "https://vault.example.com/v1/secret/data/account1",
+				ParamCredentialTokenPath: "/var/run/secrets/accounts/vault-token"},
+			allowed: true,
+		},
+		{
+			name: "token outside allowed directories",
+			parameters: map[string]string{ParamCredentialSource: CredentialSourceHTTP,
+				ParamCredentialURL:       "https://vault.example.com/v1/secret/data/account1",
+				ParamCredentialTokenPath: "/var/run/secrets/kubernetes.io/serviceaccount/token"},
+		},
+		{
+			name:       "secret is always allowed",
+			parameters: map[string]string{"accountSecret": "account1", "accountSecretNamespace": "cosi"},
+			allowed:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cache.NewCredentialProvider(tt.parameters)
+			if tt.allowed && err != nil {
+				t.Errorf("got %v, want allowed", err)
+			}
+			if !tt.allowed && status.Code(err) != codes.PermissionDenied {
+				t.Errorf("got %v, want PermissionDenied", err)
+			}
+		})
+	}
+}
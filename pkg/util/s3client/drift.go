@@ -0,0 +1,228 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+)
+
+// UserGrant is the access a BucketAccess grants an IAM user to a bucket
+type UserGrant struct {
+	UserName string
+	Actions  []string
+}
+
+// userStatement returns the statement granting principal, the IAM user userName, the actions on bucketName
+func userStatement(bucketName, userName, principal string, actions []string) RawPolicyStatement {
+	return RawPolicyStatement{
+		Sid:    statementSid(userName),
+		Effect: "Allow",
+		Principal: map[string][]string{
+			"AWS": {principal},
+		},
+		Action:   actions,
+		Resource: bucketResources(bucketName),
+	}
+}
+
+// bucketResources returns the resources of the bucket and its objects
+func bucketResources(bucketName string) []string {
+	return []string{
+		fmt.Sprintf("arn:aws:s3:::%s", bucketName),
+		fmt.Sprintf("arn:aws:s3:::%s/*", bucketName),
+	}
+}
+
+// statementSid returns the Sid of the statement of userName. Sids may only hold letters and digits.
+func statementSid(userName string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, userName)
+}
+
+// sameStrings reports whether a and b hold the same strings in any order
+func sameStrings(a, b []string) bool {
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, "\n") == strings.Join(b, "\n")
+}
+
+// driverStatementUser returns the user of users that stmt is the driver's statement for. Only
+// statements of the shape userStatement writes qualify: an Allow of actions on the bucket to a
+// single principal naming the user, with the Sid of the user or, as written before Sids were
+// added, none, and no conditions or negated elements. Statements an admin wrote or extended are
+// never considered the driver's.
+func (s *S3Client) driverStatementUser(stmt policyStatement, bucketName string, users []*iam.User) *iam.User {
+	if stmt.Effect != "Allow" || len(stmt.NotPrincipal) > 0 || len(stmt.NotAction) > 0 ||
+		len(stmt.NotResource) > 0 || len(stmt.Condition) > 0 {
+		return nil
+	}
+	var principal map[string]json.RawMessage
+	if err := json.Unmarshal(stmt.Principal, &principal); err != nil || len(principal) != 1 {
+		return nil
+	}
+	names, ok := rawStrings(principal["AWS"])
+	if !ok || len(names) != 1 {
+		return nil
+	}
+	if _, ok := rawStrings(stmt.Action); !ok {
+		return nil
+	}
+	resources, ok := rawStrings(stmt.Resource)
+	if !ok {
+		return nil
+	}
+	allowed := bucketResources(bucketName)
+	for _, resource := range resources {
+		if resource != allowed[0] && resource != allowed[1] {
+			return nil
+		}
+	}
+
+	for _, user := range users {
+		if !s.principals().Matches(names[0], user) {
+			continue
+		}
+		if stmt.Sid != "" && stmt.Sid != statementSid(aws.StringValue(user.UserName)) {
+			return nil
+		}
+		return user
+	}
+	return nil
+}
+
+// statementMatches reports whether a statement of the driver grants principal exactly actions on
+// bucketName and its objects
+func statementMatches(stmt policyStatement, bucketName, principal string, actions []string) bool {
+	var granted map[string]json.RawMessage
+	if err := json.Unmarshal(stmt.Principal, &granted); err != nil {
+		return false
+	}
+	principals, _ := rawStrings(granted["AWS"])
+	grantedActions, _ := rawStrings(stmt.Action)
+	resources, _ := rawStrings(stmt.Resource)
+	return sameStrings(principals, []string{principal}) &&
+		sameStrings(grantedActions, actions) &&
+		sameStrings(resources, bucketResources(bucketName))
+}
+
+// ReconcileBucketPolicy restores the statements of grants that are missing from the policy of
+// bucketName or were altered, and returns the users whose statement drifted. Only statements the
+// driver writes are replaced; every other statement, including ones naming several principals or
+// carrying conditions, is written back exactly as read. Users that no longer exist are skipped.
+func (s *S3Client) ReconcileBucketPolicy(ctx context.Context, bucketName string, grants []UserGrant) ([]string, error) {
+	fields := map[string]json.RawMessage{}
+	var statements []json.RawMessage
+	previousPolicy := ""
+	policy, err := s.S3.GetBucketPolicy(&s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NoSuchBucketPolicy" {
+			return nil, err
+		}
+	} else {
+		previousPolicy = aws.StringValue(policy.Policy)
+		if fields, statements, err = parseRawPolicy(previousPolicy); err != nil {
+			return nil, err
+		}
+	}
+	parsed := make([]policyStatement, len(statements))
+	for i, raw := range statements {
+		// Statements that cannot be inspected are not the driver's and are kept as they are
+		_ = json.Unmarshal(raw, &parsed[i])
+	}
+
+	var users []*iam.User
+	principals := map[string]string{}
+	actions := map[string][]string{}
+	for _, grant := range grants {
+		userOutput, err := s.IAM.GetUser(grant.UserName)
+		if err != nil {
+			klog.V(3).InfoS("skipping drift check of unknown user", "bucketName", bucketName, "username", grant.UserName, "error", err)
+			continue
+		}
+		users = append(users, userOutput.User)
+		principals[grant.UserName] = s.principals().Principal(userOutput.User)
+		actions[grant.UserName] = grant.Actions
+	}
+
+	// The statements of the driver, by user
+	owned := make([]*iam.User, len(statements))
+	byUser := map[string][]policyStatement{}
+	for i, stmt := range parsed {
+		if user := s.driverStatementUser(stmt, bucketName, users); user != nil {
+			owned[i] = user
+			userName := aws.StringValue(user.UserName)
+			byUser[userName] = append(byUser[userName], stmt)
+		}
+	}
+
+	drifted := map[string]bool{}
+	var userNames []string
+	var desired []json.RawMessage
+	for _, user := range users {
+		userName := aws.StringValue(user.UserName)
+		current := byUser[userName]
+		if len(current) == 1 && statementMatches(current[0], bucketName, principals[userName], actions[userName]) {
+			continue
+		}
+		stmt, err := json.MarshalIndent(userStatement(bucketName, userName, principals[userName], actions[userName]), "    ", "  ")
+		if err != nil {
+			return nil, err
+		}
+		drifted[userName] = true
+		userNames = append(userNames, userName)
+		desired = append(desired, stmt)
+	}
+	if len(drifted) == 0 {
+		return nil, nil
+	}
+
+	// Replace the statements of the drifted users; the deny-all baseline is dropped as on grant
+	var kept []json.RawMessage
+	for i, raw := range statements {
+		if isRawDenyAll(parsed[i]) || (owned[i] != nil && drifted[aws.StringValue(owned[i].UserName)]) {
+			continue
+		}
+		kept = append(kept, raw)
+	}
+	policyJSON := encodeRawPolicy(fields, append(kept, desired...))
+	if err := s.profile().checkPolicySize(policyJSON); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	klog.InfoS("Restoring drifted bucket policy statements", "bucketName", bucketName, "users", userNames)
+	policyStr := string(policyJSON)
+	_, err = s.S3.PutBucketPolicy(&s3.PutBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+		Policy: &policyStr,
+	})
+	s.recordPolicyAudit(ctx, audit.ActionPutBucketPolicy, bucketName, strings.Join(userNames, ","), previousPolicy, policyStr, err)
+	if err != nil {
+		return nil, err
+	}
+	return userNames, nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+// Statements an admin added to a bucket policy, which the driver must write back unchanged
+const (
+	conditionStatement = `{"Sid":"AdminTLSOnly","Effect":"Deny","Principal":"*","Action":"s3:*",` +
+		`"Resource":"arn:aws:s3:::bucket1/*","Condition":{"Bool":{"aws:SecureTransport":"false"}}}`
+	notPrincipalStatement = `{"Effect":"Deny","NotPrincipal":{"AWS":["arn:aws:iam::123456789012:user/admin"]},` +
+		`"NotAction":["s3:GetObject"],"Resource":["arn:aws:s3:::bucket1"]}`
+)
+
+// multiPrincipalStatement grants the driver user and another principal together
+func multiPrincipalStatement(userName string) string {
+	return `{"Effect":"Allow","Principal":{"AWS":["` + s3clienttest.UserARN(userName) + `","arn:aws:iam::123456789012:user/backup"]},` +
+		`"Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket1/*"}`
+}
+
+func TestReconcileBucketPolicyKeepsForeignStatements(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	client := newTestClient(t, server, ProviderMinIO)
+	const userName = "cosi-user-ba-1"
+	server.AddUser(userName, time.Now(), nil)
+
+	// The statement of the driver was narrowed to a single action by an admin
+	drifted := `{"Effect":"Allow","Principal":{"AWS":"` + s3clienttest.UserARN(userName) + `"},` +
+		`"Action":"s3:GetObject","Resource":["arn:aws:s3:::bucket1","arn:aws:s3:::bucket1/*"]}`
+	policy := `{"Version":"2012-10-17","Id":"team-policy","Statement":[` +
+		conditionStatement + `,` + notPrincipalStatement + `,` + multiPrincipalStatement(userName) + `,` + drifted + `]}`
+	server.AddBucket("bucket1", policy)
+
+	actions := []string{"s3:GetObject", "s3:PutObject"}
+	users, err := client.ReconcileBucketPolicy(context.Background(), "bucket1", []UserGrant{{UserName: userName, Actions: actions}})
+	if err != nil {
+		t.Fatalf("ReconcileBucketPolicy: %v", err)
+	}
+	if len(users) != 1 || users[0] != userName {
+		t.Fatalf("drifted users = %v, want %s", users, userName)
+	}
+
+	bucket, _ := server.Bucket("bucket1")
+	for _, stmt := range []string{conditionStatement, notPrincipalStatement, multiPrincipalStatement(userName)} {
+		if !strings.Contains(bucket.Policy, stmt) {
+			t.Errorf("statement was not kept unchanged:\n%s\npolicy:\n%s", stmt, bucket.Policy)
+		}
+	}
+	if strings.Contains(bucket.Policy, drifted) {
+		t.Errorf("drifted statement was kept:\n%s", bucket.Policy)
+	}
+
+	var written struct {
+		Version   string
+		Id        string
+		Statement []policyStatement
+	}
+	if err := json.Unmarshal([]byte(bucket.Policy), &written); err != nil {
+		t.Fatalf("policy is not valid JSON: %v\n%s", err, bucket.Policy)
+	}
+	if written.Id != "team-policy" || written.Version != "2012-10-17" {
+		t.Errorf("top-level elements were lost: %+v", written)
+	}
+	if len(written.Statement) != 4 {
+		t.Fatalf("policy has %d statements, want 4:\n%s", len(written.Statement), bucket.Policy)
+	}
+	restored := written.Statement[3]
+	if restored.Sid != statementSid(userName) || !statementMatches(restored, "bucket1", s3clienttest.UserARN(userName), actions) {
+		t.Errorf("restored statement = %+v", restored)
+	}
+
+	// The restored policy no longer drifts
+	puts := server.Count("s3:PutBucketPolicy")
+	users, err = client.ReconcileBucketPolicy(context.Background(), "bucket1", []UserGrant{{UserName: userName, Actions: actions}})
+	if err != nil || len(users) != 0 {
+		t.Errorf("second ReconcileBucketPolicy = %v, %v, want no drift", users, err)
+	}
+	if server.Count("s3:PutBucketPolicy") != puts {
+		t.Error("policy without drift was written")
+	}
+}
+
+func TestReconcileBucketPolicyRestoresMissingStatement(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	client := newTestClient(t, server, ProviderMinIO)
+	server.AddUser("cosi-user-ba-1", time.Now(), nil)
+	server.AddUser("cosi-user-ba-2", time.Now(), nil)
+	server.AddBucket("bucket1", "")
+
+	grants := []UserGrant{
+		{UserName: "cosi-user-ba-1", Actions: []string{"s3:GetObject"}},
+		{UserName: "cosi-user-ba-2", Actions: []string{"s3:*"}},
+	}
+	if err := client.AddUserToBucketPolicy(context.Background(), "bucket1", "cosi-user-ba-1", grants[0].Actions); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := client.ReconcileBucketPolicy(context.Background(), "bucket1", grants)
+	if err != nil {
+		t.Fatalf("ReconcileBucketPolicy: %v", err)
+	}
+	if len(users) != 1 || users[0] != "cosi-user-ba-2" {
+		t.Errorf("drifted users = %v, want cosi-user-ba-2", users)
+	}
+	bucket, _ := server.Bucket("bucket1")
+	_, statements, err := parseRawPolicy(bucket.Policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 2 {
+		t.Errorf("policy has %d statements, want 2:\n%s", len(statements), bucket.Policy)
+	}
+}
+
+func TestAddUserToBucketPolicyReplacesActions(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	client := newTestClient(t, server, ProviderMinIO)
+	const userName = "cosi-user-ba-1"
+	server.AddUser(userName, time.Now(), nil)
+	server.AddBucket("bucket1", `{"Version":"2012-10-17","Statement":[`+conditionStatement+`]}`)
+	ctx := context.Background()
+
+	if err := client.AddUserToBucketPolicy(ctx, "bucket1", userName, []string{"s3:*"}); err != nil {
+		t.Fatal(err)
+	}
+	puts := server.Count("s3:PutBucketPolicy")
+	if err := client.AddUserToBucketPolicy(ctx, "bucket1", userName, []string{"s3:*"}); err != nil {
+		t.Fatal(err)
+	}
+	if server.Count("s3:PutBucketPolicy") != puts {
+		t.Error("policy was written although the statement of the user was unchanged")
+	}
+
+	// Narrowed actions replace the statement of the user instead of being ignored
+	actions := []string{"s3:GetObject", "s3:ListBucket"}
+	if err := client.AddUserToBucketPolicy(ctx, "bucket1", userName, actions); err != nil {
+		t.Fatal(err)
+	}
+	bucket, _ := server.Bucket("bucket1")
+	if !strings.Contains(bucket.Policy, conditionStatement) {
+		t.Errorf("admin statement was not kept unchanged:\n%s", bucket.Policy)
+	}
+	_, statements, err := parseRawPolicy(bucket.Policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("policy has %d statements, want 2:\n%s", len(statements), bucket.Policy)
+	}
+	var stmt policyStatement
+	if err := json.Unmarshal(statements[1], &stmt); err != nil {
+		t.Fatal(err)
+	}
+	if !statementMatches(stmt, "bucket1", s3clienttest.UserARN(userName), actions) {
+		t.Errorf("statement of the user = %s, want actions %v", statements[1], actions)
+	}
+}
+
+func TestRemoveUserFromBucketPolicyKeepsForeignStatements(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	client := newTestClient(t, server, ProviderMinIO)
+	const userName = "cosi-user-ba-1"
+	server.AddUser(userName, time.Now(), nil)
+
+	// An admin Deny naming the user, with a condition and a plain string Resource
+	denyStatement := `{"Sid":"NoDeleteFromUser","Effect":"Deny","Principal":{"AWS":"` + s3clienttest.UserARN(userName) + `"},` +
+		`"Action":"s3:DeleteObject","Resource":"arn:aws:s3:::bucket1/*","Condition":{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"}}}`
+	server.AddBucket("bucket1", `{"Version":"2012-10-17","Id":"team-policy","Statement":[`+
+		denyStatement+`,`+conditionStatement+`,`+notPrincipalStatement+`,`+multiPrincipalStatement(userName)+`]}`)
+	ctx := context.Background()
+	if err := client.AddUserToBucketPolicy(ctx, "bucket1", userName, []string{"s3:GetObject"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.RemoveUserFromBucketPolicy(ctx, "bucket1", userName); err != nil {
+		t.Fatalf("RemoveUserFromBucketPolicy: %v", err)
+	}
+	bucket, _ := server.Bucket("bucket1")
+	for _, stmt := range []string{denyStatement, conditionStatement, notPrincipalStatement, multiPrincipalStatement(userName)} {
+		if !strings.Contains(bucket.Policy, stmt) {
+			t.Errorf("statement was not kept unchanged:\n%s\npolicy:\n%s", stmt, bucket.Policy)
+		}
+	}
+	fields, statements, err := parseRawPolicy(bucket.Policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 4 {
+		t.Errorf("policy has %d statements, want the 4 foreign ones:\n%s", len(statements), bucket.Policy)
+	}
+	if string(fields["Id"]) != `"team-policy"` {
+		t.Errorf("policy Id was lost:\n%s", bucket.Policy)
+	}
+
+	// Nothing of the driver is left, so a second revoke leaves the policy alone
+	puts := server.Count("s3:PutBucketPolicy")
+	if err := client.RemoveUserFromBucketPolicy(ctx, "bucket1", userName); err != nil {
+		t.Fatal(err)
+	}
+	if server.Count("s3:PutBucketPolicy") != puts {
+		t.Error("policy without statements of the user was written")
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/logging"
+)
+
+// Synthetic values returned by dry-run clients in place of backend generated ones
+const (
+	dryRunAccessKeyID     = "DRYRUNACCESSKEYID"
+	dryRunSecretAccessKey = "dry-run-secret-access-key"
+	dryRunSessionToken    = "dry-run-session-token"
+	dryRunARNPrefix       = "arn:aws:iam:::"
+	defaultDryRunSession  = time.Hour
+)
+
+// NewDryRunS3Client wraps the clients of client so that mutations are logged instead of sent to
+// the backend and answered with synthetic successful responses. Reads still reach the backend.
+func NewDryRunS3Client(client *S3Client) *S3Client {
+	dryRun := *client
+	dryRun.S3 = &dryRunS3{S3API: client.S3}
+	dryRun.IAM = &dryRunIAM{IAMClientInterface: client.IAM}
+	dryRun.STS = &dryRunSTS{}
+	if client.Admin != nil {
+		dryRun.Admin = &dryRunAdmin{}
+	}
+	dryRun.DryRun = true
+	return &dryRun
+}
+
+// dryRunS3 logs S3 mutations instead of performing them
+type dryRunS3 struct {
+	s3iface.S3API
+}
+
+func (d *dryRunS3) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	klog.InfoS("Dry run: would create bucket", "bucketName", aws.StringValue(input.Bucket))
+	return &s3.CreateBucketOutput{Location: aws.String("/" + aws.StringValue(input.Bucket))}, nil
+}
+
+func (d *dryRunS3) DeleteBucket(input *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	klog.InfoS("Dry run: would delete bucket", "bucketName", aws.StringValue(input.Bucket))
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+// GetBucketPolicy reports buckets that do not exist, typically because they were only created in
+// dry-run mode, as having no policy so that grants can be evaluated against them
+func (d *dryRunS3) GetBucketPolicy(input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	output, err := d.S3API.GetBucketPolicy(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+		return nil, awserr.New("NoSuchBucketPolicy", "dry run: bucket does not exist", err)
+	}
+	return output, err
+}
+
+func (d *dryRunS3) PutBucketPolicy(input *s3.PutBucketPolicyInput) (*s3.PutBucketPolicyOutput, error) {
+	klog.InfoS("Dry run: would put bucket policy",
+		"bucketName", aws.StringValue(input.Bucket),
+		"policy", logging.RedactPolicy(aws.StringValue(input.Policy)))
+	return &s3.PutBucketPolicyOutput{}, nil
+}
+
+func (d *dryRunS3) DeleteBucketPolicy(input *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+	klog.InfoS("Dry run: would delete bucket policy", "bucketName", aws.StringValue(input.Bucket))
+	return &s3.DeleteBucketPolicyOutput{}, nil
+}
+
+// GetBucketTagging reports buckets that only exist in dry-run mode as having no tags
+func (d *dryRunS3) GetBucketTagging(input *s3.GetBucketTaggingInput) (*s3.GetBucketTaggingOutput, error) {
+	output, err := d.S3API.GetBucketTagging(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+		return nil, awserr.New("NoSuchTagSet", "dry run: bucket does not exist", err)
+	}
+	return output, err
+}
+
+func (d *dryRunS3) PutBucketTagging(input *s3.PutBucketTaggingInput) (*s3.PutBucketTaggingOutput, error) {
+	klog.InfoS("Dry run: would tag bucket", "bucketName", aws.StringValue(input.Bucket), "tags", len(input.Tagging.TagSet))
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+
+func (d *dryRunS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	klog.InfoS("Dry run: would put object", "bucketName", aws.StringValue(input.Bucket), "key", aws.StringValue(input.Key))
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (d *dryRunS3) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	klog.InfoS("Dry run: would delete object", "bucketName", aws.StringValue(input.Bucket), "key", aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// dryRunIAM logs IAM mutations instead of performing them
+type dryRunIAM struct {
+	IAMClientInterface
+}
+
+// GetUser answers for users the driver would have created with a synthetic user, so that the
+// policy statements granting them access can still be rendered
+func (d *dryRunIAM) GetUser(userName string) (*iam.GetUserOutput, error) {
+	output, err := d.IAMClientInterface.GetUser(userName)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		return &iam.GetUserOutput{User: dryRunUser(userName)}, nil
+	}
+	return output, err
+}
+
+// ListAccessKeys reports users that only exist in dry-run mode as having no access keys
+func (d *dryRunIAM) ListAccessKeys(input *iam.ListAccessKeysInput) (*iam.ListAccessKeysOutput, error) {
+	output, err := d.IAMClientInterface.ListAccessKeys(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		return &iam.ListAccessKeysOutput{}, nil
+	}
+	return output, err
+}
+
+// ListUserTags reports users that only exist in dry-run mode as having no tags
+func (d *dryRunIAM) ListUserTags(userName string) (map[string]string, error) {
+	tags, err := d.IAMClientInterface.ListUserTags(userName)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+		return map[string]string{}, nil
+	}
+	return tags, err
+}
+
+func (d *dryRunIAM) CreateUser(userName string) (*iam.CreateUserOutput, error) {
+	klog.InfoS("Dry run: would create IAM user", "userName", userName)
+	return &iam.CreateUserOutput{User: dryRunUser(userName)}, nil
+}
+
+func (d *dryRunIAM) DeleteUser(userName string) error {
+	klog.InfoS("Dry run: would delete IAM user and its access keys", "userName", userName)
+	return nil
+}
+
+func (d *dryRunIAM) CreateAccessKey(userName string) (*iam.CreateAccessKeyOutput, error) {
+	klog.InfoS("Dry run: would create access key", "userName", userName)
+	return &iam.CreateAccessKeyOutput{AccessKey: &iam.AccessKey{
+		UserName:        aws.String(userName),
+		AccessKeyId:     aws.String(dryRunAccessKeyID),
+		SecretAccessKey: aws.String(dryRunSecretAccessKey),
+		Status:          aws.String(iam.StatusTypeActive),
+		CreateDate:      aws.Time(time.Now()),
+	}}, nil
+}
+
+func (d *dryRunIAM) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	roleName := aws.StringValue(input.RoleName)
+	klog.InfoS("Dry run: would create IAM role", "roleName", roleName)
+	return &iam.CreateRoleOutput{Role: &iam.Role{
+		RoleName:   input.RoleName,
+		RoleId:     aws.String("dry-run-" + roleName),
+		Arn:        aws.String(dryRunARNPrefix + "role/" + roleName),
+		CreateDate: aws.Time(time.Now()),
+	}}, nil
+}
+
+func (d *dryRunIAM) UpdateAssumeRolePolicy(roleName, policyDocument string) error {
+	klog.InfoS("Dry run: would update role trust policy", "roleName", roleName)
+	return nil
+}
+
+func (d *dryRunIAM) PutRolePolicy(input *iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error) {
+	klog.InfoS("Dry run: would put role policy",
+		"roleName", aws.StringValue(input.RoleName),
+		"policyName", aws.StringValue(input.PolicyName))
+	return &iam.PutRolePolicyOutput{}, nil
+}
+
+func (d *dryRunIAM) DeleteRole(roleName string) error {
+	klog.InfoS("Dry run: would delete IAM role and its inline policies", "roleName", roleName)
+	return nil
+}
+
+func (d *dryRunIAM) TagUser(userName string, tags map[string]string) error {
+	klog.InfoS("Dry run: would tag IAM user", "userName", userName, "tags", len(tags))
+	return nil
+}
+
+// dryRunUser is the synthetic user returned for users that do not exist on the backend
+func dryRunUser(userName string) *iam.User {
+	return &iam.User{
+		UserName:   aws.String(userName),
+		UserId:     aws.String("dry-run-" + userName),
+		Arn:        aws.String(dryRunARNPrefix + "user/" + userName),
+		CreateDate: aws.Time(time.Now()),
+	}
+}
+
+// dryRunSTS issues synthetic temporary credentials instead of assuming roles
+type dryRunSTS struct{}
+
+func (d *dryRunSTS) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	klog.InfoS("Dry run: would assume role",
+		"roleArn", aws.StringValue(input.RoleArn),
+		"sessionName", aws.StringValue(input.RoleSessionName))
+	duration := defaultDryRunSession
+	if input.DurationSeconds != nil {
+		duration = time.Duration(aws.Int64Value(input.DurationSeconds)) * time.Second
+	}
+	return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String(dryRunAccessKeyID),
+		SecretAccessKey: aws.String(dryRunSecretAccessKey),
+		SessionToken:    aws.String(dryRunSessionToken),
+		Expiration:      aws.Time(time.Now().Add(duration)),
+	}}, nil
+}
+
+// dryRunAdmin logs provider admin operations instead of performing them
+type dryRunAdmin struct{}
+
+func (d *dryRunAdmin) SetBucketQuota(_ context.Context, bucketName string, quota BucketQuota) error {
+	klog.InfoS("Dry run: would set bucket quota", "bucketName", bucketName, "maxBytes", quota.MaxBytes, "maxObjects", quota.MaxObjects)
+	return nil
+}
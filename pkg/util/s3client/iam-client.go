@@ -8,7 +8,6 @@ package s3client
 
 import (
 	"context"
-	"net/http"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -19,6 +18,9 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/logging"
 )
 
 // IAMClientInterface is an interface for IAM operations
@@ -29,6 +31,14 @@ type IAMClientInterface interface {
 	CreateAccessKey(userName string) (*iam.CreateAccessKeyOutput, error)
 	ListAccessKeys(input *iam.ListAccessKeysInput) (*iam.ListAccessKeysOutput, error)
 	GetAccessKeyLastUsed(input *iam.GetAccessKeyLastUsedInput) (*iam.GetAccessKeyLastUsedOutput, error)
+	GetRole(roleName string) (*iam.GetRoleOutput, error)
+	CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error)
+	UpdateAssumeRolePolicy(roleName, policyDocument string) error
+	DeleteRole(roleName string) error
+	PutRolePolicy(input *iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error)
+	ListUsers(namePrefix string) ([]*iam.User, error)
+	TagUser(userName string, tags map[string]string) error
+	ListUserTags(userName string) (map[string]string, error)
 }
 
 // IAMClient wraps the IAM API
@@ -43,25 +53,11 @@ func NewIAMClient(params *S3ClientParams, debug bool) (*IAMClient, error) {
 		logLevel = aws.LogDebug
 	}
 
-	// Create base HTTP client
-	client := http.Client{
-		Timeout: HttpTimeOut,
-	}
-
-	// Handle TLS configuration
-	klog.V(5).InfoS("Creating IAM client with endpoint", "endpoint", params.Endpoint)
-	tlsEnabled := false
-	insecure := true
-	if strings.HasPrefix(params.Endpoint, "https") {
-		klog.V(5).InfoS("Using secure endpoint")
-		insecure = false
-		if len(params.TlsCert) > 0 {
-			klog.V(5).InfoS("Using TLS certificate")
-			tlsEnabled = true
-			client.Transport = buildTransportTLS(params.TlsCert, insecure)
-		}
-	} else {
-		klog.V(5).InfoS("Using insecure endpoint", "endpoint", params.Endpoint)
+	endpoint := params.GetFullIAMEndpoint()
+	klog.V(5).InfoS("Creating IAM client with endpoint", "endpoint", endpoint)
+	client, err := newHTTPClient(params, endpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create IAM session with IAM endpoint
@@ -69,16 +65,18 @@ func NewIAMClient(params *S3ClientParams, debug bool) (*IAMClient, error) {
 		aws.NewConfig().
 			WithRegion("us-east-1").
 			WithCredentials(credentials.NewStaticCredentials(params.AccessKey, params.SecretKey, "")).
-			WithEndpoint(params.GetFullIAMEndpoint()).
+			WithEndpoint(endpoint).
 			WithMaxRetries(5).
-			WithDisableSSL(!tlsEnabled).
-			WithHTTPClient(&client).
+			WithDisableSSL(!isSecureEndpoint(endpoint)).
+			WithHTTPClient(client).
 			WithLogLevel(logLevel).
+			WithLogger(logging.AWSLogger()).
 			WithS3ForcePathStyle(true),
 	)
 	if err != nil {
 		return nil, err
 	}
+	instrumentSession(iamSession, serviceIAM)
 
 	return &IAMClient{
 		api: iam.New(iamSession),
@@ -124,6 +122,94 @@ func (a *IAMClient) DeleteAccessKey(input *iam.DeleteAccessKeyInput) (*iam.Delet
 	return a.api.DeleteAccessKey(input)
 }
 
+// GetRole gets a role by name
+func (a *IAMClient) GetRole(roleName string) (*iam.GetRoleOutput, error) {
+	return a.api.GetRole(&iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+}
+
+// CreateRole creates a new IAM role
+func (a *IAMClient) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	return a.api.CreateRole(input)
+}
+
+// UpdateAssumeRolePolicy replaces the trust policy of a role
+func (a *IAMClient) UpdateAssumeRolePolicy(roleName, policyDocument string) error {
+	_, err := a.api.UpdateAssumeRolePolicy(&iam.UpdateAssumeRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyDocument: aws.String(policyDocument),
+	})
+	return err
+}
+
+// PutRolePolicy creates or replaces an inline policy of a role
+func (a *IAMClient) PutRolePolicy(input *iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error) {
+	return a.api.PutRolePolicy(input)
+}
+
+// ListUsers lists the users whose name starts with namePrefix
+func (a *IAMClient) ListUsers(namePrefix string) ([]*iam.User, error) {
+	var users []*iam.User
+	err := a.api.ListUsersPages(&iam.ListUsersInput{}, func(page *iam.ListUsersOutput, _ bool) bool {
+		for _, user := range page.Users {
+			if strings.HasPrefix(aws.StringValue(user.UserName), namePrefix) {
+				users = append(users, user)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		klog.ErrorS(err, "failed to list users")
+		return nil, err
+	}
+	return users, nil
+}
+
+// DeleteRole deletes an IAM role after removing its inline policies
+func (a *IAMClient) DeleteRole(roleName string) error {
+	klog.InfoS("Attempting to delete IAM role", "roleName", roleName)
+
+	policies, err := a.api.ListRolePolicies(&iam.ListRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			klog.InfoS("Role does not exist, nothing to delete", "roleName", roleName)
+			return nil
+		}
+		klog.ErrorS(err, "failed to list role policies", "roleName", roleName)
+		return err
+	}
+
+	for _, policyName := range policies.PolicyNames {
+		_, err := a.api.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
+			RoleName:   aws.String(roleName),
+			PolicyName: policyName,
+		})
+		if err != nil {
+			klog.ErrorS(err, "failed to delete role policy",
+				"roleName", roleName,
+				"policyName", aws.StringValue(policyName))
+			return err
+		}
+	}
+
+	_, err = a.api.DeleteRole(&iam.DeleteRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			return nil
+		}
+		klog.ErrorS(err, "failed to delete role", "roleName", roleName)
+		return err
+	}
+
+	klog.InfoS("Successfully deleted IAM role", "roleName", roleName)
+	return nil
+}
+
 // DeleteUser deletes an IAM user
 func (a *IAMClient) DeleteUser(userName string) error {
 	klog.InfoS("Attempting to delete IAM user", "username", userName)
@@ -193,6 +279,7 @@ func (s *S3Client) EnsureIAMUser(ctx context.Context, userName string) (*iam.Acc
 	if err != nil {
 		klog.InfoS("User does not exist, attempting to create", "userName", userName, "error", err)
 		_, err = s.IAM.CreateUser(userName)
+		s.recordAudit(ctx, audit.Event{Action: audit.ActionCreateUser, User: userName}, err)
 		if err != nil {
 			klog.ErrorS(err, "Failed to create IAM user", "userName", userName)
 			return nil, status.Error(codes.Internal, "Failed to create IAM user")
@@ -225,6 +312,7 @@ func (s *S3Client) EnsureIAMUser(ctx context.Context, userName string) (*iam.Acc
 				klog.InfoS("Using existing access key", "userName", userName, "accessKeyId", *latestKey.AccessKeyId)
 				// Create a new access key to get the secret
 				createKeyOutput, err := s.IAM.CreateAccessKey(userName)
+				s.recordAudit(ctx, accessKeyAuditEvent(userName, createKeyOutput), err)
 				if err != nil {
 					klog.ErrorS(err, "Failed to create new access key", "userName", userName)
 					return nil, status.Error(codes.Internal, "Failed to create new access key")
@@ -236,6 +324,7 @@ func (s *S3Client) EnsureIAMUser(ctx context.Context, userName string) (*iam.Acc
 
 	klog.InfoS("Creating access keys for user", "userName", userName)
 	accessKeyResult, err := s.IAM.CreateAccessKey(userName)
+	s.recordAudit(ctx, accessKeyAuditEvent(userName, accessKeyResult), err)
 	if err != nil {
 		klog.ErrorS(err, "Failed to create access key", "userName", userName)
 		return nil, status.Error(codes.Internal, "Failed to create access key")
@@ -243,3 +332,19 @@ func (s *S3Client) EnsureIAMUser(ctx context.Context, userName string) (*iam.Acc
 
 	return accessKeyResult.AccessKey, nil
 }
+
+// DeleteIAMUser deletes the IAM user and its access keys, recording the mutation in the audit log
+func (s *S3Client) DeleteIAMUser(ctx context.Context, userName string) error {
+	err := s.IAM.DeleteUser(userName)
+	s.recordAudit(ctx, audit.Event{Action: audit.ActionDeleteUser, User: userName}, err)
+	return err
+}
+
+// accessKeyAuditEvent builds the audit event for a CreateAccessKey call; the secret is never recorded
+func accessKeyAuditEvent(userName string, output *iam.CreateAccessKeyOutput) audit.Event {
+	event := audit.Event{Action: audit.ActionCreateAccessKey, User: userName}
+	if output != nil && output.AccessKey != nil {
+		event.AccessKeyID = aws.StringValue(output.AccessKey.AccessKeyId)
+	}
+	return event
+}
@@ -0,0 +1,52 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/metrics"
+)
+
+// Service labels for backend call metrics
+const (
+	serviceS3  = "s3"
+	serviceIAM = "iam"
+	serviceSTS = "sts"
+)
+
+// instrumentSession records a backend call metric for every API request made through the session.
+// The Complete handler runs once per operation, after all retries have finished.
+func instrumentSession(sess *session.Session, service string) {
+	sess.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "s3-iam-cosi.metrics",
+		Fn: func(r *request.Request) {
+			code := metrics.CodeOK
+			if r.Error != nil {
+				code = metrics.CodeUnknown
+				if aerr, ok := r.Error.(awserr.Error); ok && aerr.Code() != "" {
+					code = aerr.Code()
+				}
+			}
+			metrics.ObserveBackendCall(service, r.Operation.Name, endpointLabel(r.ClientInfo.Endpoint), code, time.Since(r.Time))
+		},
+	})
+}
+
+// endpointLabel reduces an endpoint URL to its host:port to keep label cardinality low
+func endpointLabel(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	return u.Host
+}
@@ -18,17 +18,42 @@ type S3ClientParams struct {
 	Endpoint    string
 	S3Port      string
 	IAMPort     string
+	IAMEndpoint string
+	STSEndpoint string
 	AccountName string
 	AccessKey   string
 	SecretKey   string
 	TlsCert     []byte
 	Region      string
+	Provider    string
+	Profile     *ProviderProfile
+
+	// How IAM users are referenced in bucket policies
+	PrincipalFormat PrincipalFormat
+	AccountID       string
+	Tenant          string
+
+	// Limits on what the driver provisions for the account
+	Quotas Quotas
+
+	// TLS options for https endpoints
+	TlsClientCert      []byte
+	TlsClientKey       []byte
+	InsecureSkipVerify bool
+}
+
+var portPattern = regexp.MustCompile(`:\d+`)
+
+// hasPort reports whether endpoint already carries an explicit port
+func hasPort(endpoint string) bool {
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")
+	return portPattern.MatchString(endpoint)
 }
 
 // GetFullEndpoint returns the complete endpoint URL with port if needed
 func (p *S3ClientParams) GetFullEndpoint() string {
 	// Check if the endpoint already contains a port (contains a colon followed by digits)
-	if strings.Contains(p.Endpoint, ":") && regexp.MustCompile(`:\d+`).MatchString(p.Endpoint) {
+	if hasPort(p.Endpoint) {
 		return p.Endpoint
 	}
 
@@ -52,12 +77,19 @@ func (p *S3ClientParams) GetFullEndpoint() string {
 	return protocol + finalEndpoint + ":" + p.S3Port
 }
 
-// GetFullIAMEndpoint returns the complete IAM endpoint URL with port if needed
+// GetFullIAMEndpoint returns the complete IAM endpoint URL. An explicit IAMEndpoint is used as is;
+// otherwise IAM is assumed to be served on the S3 host, on IAMPort if the endpoint has no port.
 func (p *S3ClientParams) GetFullIAMEndpoint() string {
-	// Extract the protocol (http:// or https://)
+	if p.IAMEndpoint != "" {
+		klog.V(5).InfoS("IAM endpoint configured explicitly", "finalEndpoint", p.IAMEndpoint)
+		return p.IAMEndpoint
+	}
+
+	// Keep the protocol of the S3 endpoint, defaulting to https
 	protocol := "https://"
 	endpoint := p.Endpoint
 	if strings.HasPrefix(endpoint, "http://") {
+		protocol = "http://"
 		endpoint = strings.TrimPrefix(endpoint, "http://")
 	} else if strings.HasPrefix(endpoint, "https://") {
 		endpoint = strings.TrimPrefix(endpoint, "https://")
@@ -70,7 +102,7 @@ func (p *S3ClientParams) GetFullIAMEndpoint() string {
 		"iamPort", p.IAMPort)
 
 	// Check if the endpoint already contains a port
-	if strings.Contains(endpoint, ":") && regexp.MustCompile(`:\d+`).MatchString(endpoint) {
+	if hasPort(endpoint) {
 		finalEndpoint := protocol + endpoint
 		klog.V(5).InfoS("IAM endpoint with existing port", "finalEndpoint", finalEndpoint)
 		return finalEndpoint
@@ -88,3 +120,12 @@ func (p *S3ClientParams) GetFullIAMEndpoint() string {
 	klog.V(5).InfoS("IAM endpoint with added port", "finalEndpoint", finalEndpoint)
 	return finalEndpoint
 }
+
+// GetFullSTSEndpoint returns the STS endpoint URL. Without an explicit STSEndpoint,
+// STS is assumed to be served alongside IAM, as S3-compatible backends do.
+func (p *S3ClientParams) GetFullSTSEndpoint() string {
+	if p.STSEndpoint != "" {
+		return p.STSEndpoint
+	}
+	return p.GetFullIAMEndpoint()
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+// PrincipalRaw uses the user name, qualified with the tenant if one is configured (tenant$user)
+const PrincipalRaw PrincipalFormat = "raw"
+
+// arnUserPrincipal is the format of an account-qualified user ARN
+const arnUserPrincipal = "arn:aws:iam::%s:user/%s"
+
+// ParsePrincipalFormat validates the PrincipalFormat key of an account Secret
+func ParsePrincipalFormat(format string) (PrincipalFormat, error) {
+	switch f := PrincipalFormat(format); f {
+	case PrincipalUserID, PrincipalARN, PrincipalRaw:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown principal format %q", format)
+	}
+}
+
+// PrincipalFormatter renders IAM users as bucket policy principals
+type PrincipalFormatter struct {
+	Format PrincipalFormat
+	// AccountID qualifies constructed ARNs; when empty the ARN reported by the backend is used
+	AccountID string
+	// Tenant qualifies raw user names
+	Tenant string
+}
+
+// Principal returns the principal user is granted access under
+func (f *PrincipalFormatter) Principal(user *iam.User) string {
+	switch f.Format {
+	case PrincipalARN:
+		if f.AccountID != "" || aws.StringValue(user.Arn) == "" {
+			return fmt.Sprintf(arnUserPrincipal, f.AccountID, aws.StringValue(user.UserName))
+		}
+		return aws.StringValue(user.Arn)
+	case PrincipalRaw:
+		if f.Tenant != "" {
+			return f.Tenant + "$" + aws.StringValue(user.UserName)
+		}
+		return aws.StringValue(user.UserName)
+	default:
+		return aws.StringValue(user.UserId)
+	}
+}
+
+// Matches reports whether principal refers to user in any format, so that statements written
+// before the format of an account was changed are still recognized
+func (f *PrincipalFormatter) Matches(principal string, user *iam.User) bool {
+	if principal == "" {
+		return false
+	}
+	candidates := []string{f.Principal(user), aws.StringValue(user.UserId), aws.StringValue(user.Arn)}
+	for _, candidate := range candidates {
+		if principal == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// principals returns the principal formatter of the client, following its provider profile
+func (s *S3Client) principals() *PrincipalFormatter {
+	if s.Principals == nil {
+		return &PrincipalFormatter{Format: s.profile().PrincipalFormat}
+	}
+	return s.Principals
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Supported S3-compatible providers, selected with the Provider key of the account Secret
+const (
+	ProviderAWS      = "aws"
+	ProviderCephRGW  = "ceph-rgw"
+	ProviderMinIO    = "minio"
+	ProviderNooBaa   = "noobaa"
+	ProviderIBMScale = "ibm-scale"
+	ProviderIBMCOS   = "ibm-cos"
+)
+
+// PrincipalFormat selects how IAM users are named in bucket policy principals
+type PrincipalFormat string
+
+const (
+	// PrincipalUserID uses the unique ID returned by GetUser
+	PrincipalUserID PrincipalFormat = "userId"
+	// PrincipalARN uses the user ARN returned by GetUser
+	PrincipalARN PrincipalFormat = "arn"
+)
+
+// ProviderProfile captures the behaviors that differ between S3-compatible backends
+type ProviderProfile struct {
+	// Name is the provider the profile applies to, empty for the generic profile
+	Name string
+	// PrincipalFormat is how users are referenced in bucket policies unless the account Secret overrides it
+	PrincipalFormat PrincipalFormat
+	// DenyAllBaseline is whether a new bucket starts with a deny-all policy that the first grant
+	// replaces. Backends that apply it to the account itself lock the bucket, so no profile enables
+	// it; accounts opt in with the DenyAllBaseline key of the account Secret.
+	DenyAllBaseline bool
+	// IAMOnS3Port is whether IAM is served on the S3 port when IAMPort is not set
+	IAMOnS3Port bool
+	// IAMPortHTTPS and IAMPortHTTP are the IAM ports used when IAMPort is not set
+	IAMPortHTTPS string
+	IAMPortHTTP  string
+	// IAMEndpoint and STSEndpoint are used when the account Secret does not set them
+	IAMEndpoint string
+	STSEndpoint string
+	// MaxPolicySize is the largest bucket policy in bytes the backend accepts, 0 if unlimited
+	MaxPolicySize int
+	// PathStyle is whether buckets must be addressed path-style
+	PathStyle bool
+}
+
+// genericProfile matches the behavior of the driver before providers could be declared
+var genericProfile = ProviderProfile{
+	PrincipalFormat: PrincipalUserID,
+	PathStyle:       true,
+}
+
+var providerProfiles = map[string]ProviderProfile{
+	ProviderAWS: {
+		Name:            ProviderAWS,
+		PrincipalFormat: PrincipalARN,
+		IAMEndpoint:     "https://iam.amazonaws.com",
+		STSEndpoint:     "https://sts.amazonaws.com",
+		MaxPolicySize:   20 * 1024,
+	},
+	ProviderCephRGW: {
+		Name:            ProviderCephRGW,
+		PrincipalFormat: PrincipalARN,
+		IAMOnS3Port:     true,
+		MaxPolicySize:   20 * 1024,
+		PathStyle:       true,
+	},
+	ProviderMinIO: {
+		Name:            ProviderMinIO,
+		PrincipalFormat: PrincipalARN,
+		IAMOnS3Port:     true,
+		MaxPolicySize:   20 * 1024,
+		PathStyle:       true,
+	},
+	ProviderNooBaa: {
+		Name:            ProviderNooBaa,
+		PrincipalFormat: PrincipalUserID,
+		IAMPortHTTPS:    "7005",
+		IAMPortHTTP:     "7004",
+		MaxPolicySize:   20 * 1024,
+		PathStyle:       true,
+	},
+	ProviderIBMScale: {
+		Name:            ProviderIBMScale,
+		PrincipalFormat: PrincipalUserID,
+		IAMPortHTTPS:    "7005",
+		IAMPortHTTP:     "7004",
+		MaxPolicySize:   20 * 1024,
+		PathStyle:       true,
+	},
+	ProviderIBMCOS: {
+		Name:            ProviderIBMCOS,
+		PrincipalFormat: PrincipalARN,
+		MaxPolicySize:   20 * 1024,
+		PathStyle:       true,
+	},
+}
+
+// GetProviderProfile returns the profile of provider, or the generic profile when provider is empty
+func GetProviderProfile(provider string) (*ProviderProfile, error) {
+	if provider == "" {
+		profile := genericProfile
+		return &profile, nil
+	}
+	profile, ok := providerProfiles[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", provider)
+	}
+	return &profile, nil
+}
+
+// defaultIAMPort returns the IAM port to use for endpoint when the account Secret sets none
+func (p *ProviderProfile) defaultIAMPort(endpoint, s3Port string) string {
+	if p.IAMOnS3Port {
+		return s3Port
+	}
+	if isSecureEndpoint(endpoint) {
+		return p.IAMPortHTTPS
+	}
+	return p.IAMPortHTTP
+}
+
+// checkPolicySize rejects policies the provider would refuse, measured without whitespace
+func (p *ProviderProfile) checkPolicySize(policy []byte) error {
+	if p.MaxPolicySize == 0 {
+		return nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, policy); err != nil {
+		return err
+	}
+	if compact.Len() > p.MaxPolicySize {
+		return fmt.Errorf("bucket policy of %d bytes exceeds the %d byte limit of the provider", compact.Len(), p.MaxPolicySize)
+	}
+	return nil
+}
+
+// profile returns the provider profile of the client, falling back to the generic profile
+func (s *S3Client) profile() *ProviderProfile {
+	if s.Profile == nil {
+		profile := genericProfile
+		return &profile
+	}
+	return s.Profile
+}
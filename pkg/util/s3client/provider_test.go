@@ -0,0 +1,144 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestProviderProfileEndpoints(t *testing.T) {
+	tests := []struct {
+		provider  string
+		endpoint  string
+		wantIAM   string
+		wantSTS   string
+		pathStyle bool
+	}{
+		{provider: ProviderAWS, endpoint: "https://s3.us-east-1.amazonaws.com",
+			wantIAM: "https://iam.amazonaws.com", wantSTS: "https://sts.amazonaws.com"},
+		{provider: ProviderCephRGW, endpoint: "https://rgw.example.com",
+			wantIAM: "https://rgw.example.com", wantSTS: "https://rgw.example.com", pathStyle: true},
+		{provider: ProviderMinIO, endpoint: "http://minio.example.com:9000",
+			wantIAM: "http://minio.example.com:9000", wantSTS: "http://minio.example.com:9000", pathStyle: true},
+		{provider: ProviderNooBaa, endpoint: "https://s3.noobaa.example.com",
+			wantIAM: "https://s3.noobaa.example.com:7005", wantSTS: "https://s3.noobaa.example.com:7005", pathStyle: true},
+		{provider: ProviderIBMScale, endpoint: "http://scale.example.com",
+			wantIAM: "http://scale.example.com:7004", wantSTS: "http://scale.example.com:7004", pathStyle: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			params, err := FetchParameters(map[string][]byte{
+				"Endpoint":    []byte(tt.endpoint),
+				"AccountName": []byte("account1"),
+				"AccessKey":   []byte("AKIA1"),
+				"SecretKey":   []byte("secret"),
+				"Provider":    []byte(tt.provider),
+			})
+			if err != nil {
+				t.Fatalf("FetchParameters: %v", err)
+			}
+			if got := params.GetFullIAMEndpoint(); got != tt.wantIAM {
+				t.Errorf("IAM endpoint = %q, want %q", got, tt.wantIAM)
+			}
+			if got := params.GetFullSTSEndpoint(); got != tt.wantSTS {
+				t.Errorf("STS endpoint = %q, want %q", got, tt.wantSTS)
+			}
+			if params.Profile.PathStyle != tt.pathStyle {
+				t.Errorf("PathStyle = %v, want %v", params.Profile.PathStyle, tt.pathStyle)
+			}
+			if params.Profile.DenyAllBaseline {
+				t.Error("deny-all baseline enabled without the DenyAllBaseline key")
+			}
+		})
+	}
+}
+
+func TestProviderProfileGrantAndRevoke(t *testing.T) {
+	tests := []struct {
+		provider     string
+		arnPrincipal bool
+	}{
+		{provider: "", arnPrincipal: false},
+		{provider: ProviderCephRGW, arnPrincipal: true},
+		{provider: ProviderMinIO, arnPrincipal: true},
+		{provider: ProviderNooBaa, arnPrincipal: false},
+		{provider: ProviderIBMScale, arnPrincipal: false},
+		{provider: ProviderIBMCOS, arnPrincipal: true},
+	}
+	for _, tt := range tests {
+		name := tt.provider
+		if name == "" {
+			name = "generic"
+		}
+		t.Run(name, func(t *testing.T) {
+			server := s3clienttest.NewServer(t)
+			client := newTestClient(t, server, tt.provider)
+			ctx := context.Background()
+
+			if err := client.CreateBucket("bucket1"); err != nil {
+				t.Fatalf("CreateBucket: %v", err)
+			}
+			if _, err := client.EnsureIAMUser(ctx, "cosi-user-ba-1"); err != nil {
+				t.Fatalf("EnsureIAMUser: %v", err)
+			}
+			if err := client.AddUserToBucketPolicy(ctx, "bucket1", "cosi-user-ba-1", []string{"s3:GetObject"}); err != nil {
+				t.Fatalf("AddUserToBucketPolicy: %v", err)
+			}
+
+			bucket, _ := server.Bucket("bucket1")
+			user, _ := server.User("cosi-user-ba-1")
+			want := user.ID
+			if tt.arnPrincipal {
+				want = s3clienttest.UserARN("cosi-user-ba-1")
+			}
+			var policy BucketPolicy
+			if err := json.Unmarshal([]byte(bucket.Policy), &policy); err != nil {
+				t.Fatalf("bucket policy: %v", err)
+			}
+			if len(policy.Statement) != 1 {
+				t.Fatalf("policy has %d statements, want 1:\n%s", len(policy.Statement), bucket.Policy)
+			}
+			if !strings.Contains(bucket.Policy, `"`+want+`"`) {
+				t.Errorf("policy does not grant principal %q:\n%s", want, bucket.Policy)
+			}
+
+			// Revoking the last grant leaves the bucket without a policy rather than denying all
+			if err := client.RemoveUserFromBucketPolicy(ctx, "bucket1", "cosi-user-ba-1"); err != nil {
+				t.Fatalf("RemoveUserFromBucketPolicy: %v", err)
+			}
+			if bucket, _ := server.Bucket("bucket1"); bucket.Policy != "" {
+				t.Errorf("policy after revoke = %s, want none", bucket.Policy)
+			}
+		})
+	}
+}
+
+func TestDenyAllBaselineOptIn(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	data := server.SecretData(ProviderMinIO)
+	data["DenyAllBaseline"] = []byte("true")
+	params, err := FetchParameters(data)
+	if err != nil {
+		t.Fatalf("FetchParameters: %v", err)
+	}
+	if !params.Profile.DenyAllBaseline {
+		t.Fatal("DenyAllBaseline key did not enable the deny-all baseline")
+	}
+	if other, _ := GetProviderProfile(ProviderMinIO); other.DenyAllBaseline {
+		t.Error("the opt-in of one account changed the shared provider profile")
+	}
+
+	data["DenyAllBaseline"] = []byte("sometimes")
+	if _, err := FetchParameters(data); err == nil {
+		t.Error("FetchParameters accepted an invalid DenyAllBaseline")
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Quotas limits what the driver provisions for an account or tenant. Zero means unlimited.
+type Quotas struct {
+	// MaxBuckets is the number of buckets that may be provisioned
+	MaxBuckets int `json:"maxBuckets,omitempty"`
+	// MaxUsers is the number of IAM users, one per KEY authenticated BucketAccess, that may be created
+	MaxUsers int `json:"maxUsers,omitempty"`
+	// MaxGrants is the number of BucketAccesses that may be granted, whatever their authentication
+	MaxGrants int `json:"maxGrants,omitempty"`
+	// MaxGrantsPerBucket is the number of BucketAccesses that may be granted on a single bucket
+	MaxGrantsPerBucket int `json:"maxGrantsPerBucket,omitempty"`
+}
+
+// parseQuotas reads the MaxBuckets, MaxUsers, MaxGrants and MaxGrantsPerBucket keys of an account Secret
+func parseQuotas(secretData map[string][]byte) (Quotas, error) {
+	var quotas Quotas
+	for key, limit := range map[string]*int{
+		"MaxBuckets":         &quotas.MaxBuckets,
+		"MaxUsers":           &quotas.MaxUsers,
+		"MaxGrants":          &quotas.MaxGrants,
+		"MaxGrantsPerBucket": &quotas.MaxGrantsPerBucket,
+	} {
+		value := string(secretData[key])
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return Quotas{}, fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+		}
+		*limit = n
+	}
+	return quotas, nil
+}
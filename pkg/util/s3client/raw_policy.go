@@ -7,10 +7,15 @@ Licensed under the MIT License.
 package s3client
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
 )
 
 // RawBucketPolicy represents a raw S3 bucket policy
@@ -21,6 +26,7 @@ type RawBucketPolicy struct {
 
 // RawPolicyStatement represents a raw S3 policy statement
 type RawPolicyStatement struct {
+	Sid       string      `json:"Sid,omitempty"`
 	Effect    string      `json:"Effect"`
 	Principal interface{} `json:"Principal"`
 	Action    interface{} `json:"Action"`
@@ -38,6 +44,15 @@ func (s *S3Client) PutRawBucketPolicy(bucket string, policy string) (*s3.PutBuck
 	return s.S3.PutBucketPolicy(p)
 }
 
+// PutDenyAllBaseline applies the deny-all baseline policy to the bucket, replacing previousPolicy,
+// and records the mutation in the audit log
+func (s *S3Client) PutDenyAllBaseline(ctx context.Context, bucket, previousPolicy string) error {
+	policy := NewRawDenyAllPolicy(bucket)
+	_, err := s.PutRawBucketPolicy(bucket, policy)
+	s.recordPolicyAudit(ctx, audit.ActionPutBucketPolicy, bucket, "", previousPolicy, policy, err)
+	return err
+}
+
 // NewRawDenyAllPolicy creates a raw deny-all policy for a bucket
 func NewRawDenyAllPolicy(bucketName string) string {
 	policy := RawBucketPolicy{
@@ -79,3 +94,96 @@ func NewRawAllowAccountPolicy(bucketName string, accountUser string) string {
 	policyJSON, _ := json.MarshalIndent(policy, "", "  ")
 	return string(policyJSON)
 }
+
+// policyStatement is a statement read from a bucket policy for inspection only. Every element is
+// kept raw, so that statements the driver does not own can be written back exactly as read.
+type policyStatement struct {
+	Sid          string          `json:"Sid"`
+	Effect       string          `json:"Effect"`
+	Principal    json.RawMessage `json:"Principal"`
+	NotPrincipal json.RawMessage `json:"NotPrincipal"`
+	Action       json.RawMessage `json:"Action"`
+	NotAction    json.RawMessage `json:"NotAction"`
+	Resource     json.RawMessage `json:"Resource"`
+	NotResource  json.RawMessage `json:"NotResource"`
+	Condition    json.RawMessage `json:"Condition"`
+}
+
+// rawStrings returns the strings of a policy element that is either a string or a list of strings
+func rawStrings(raw json.RawMessage) ([]string, bool) {
+	if len(raw) == 0 {
+		return nil, false
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, true
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, true
+	}
+	return nil, false
+}
+
+// isRawDenyAll reports whether stmt is the deny-all baseline written by NewRawDenyAllPolicy
+func isRawDenyAll(stmt policyStatement) bool {
+	principals, _ := rawStrings(stmt.Principal)
+	actions, _ := rawStrings(stmt.Action)
+	return stmt.Effect == "Deny" && len(principals) == 1 && principals[0] == "*" &&
+		len(actions) == 1 && actions[0] == "s3:*" && len(stmt.Condition) == 0
+}
+
+// parseRawPolicy splits a bucket policy into its top-level elements and its statements, all kept
+// raw. A single statement object is returned as a list of one.
+func parseRawPolicy(policy string) (map[string]json.RawMessage, []json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(policy), &fields); err != nil {
+		return nil, nil, err
+	}
+	raw := bytes.TrimSpace(fields["Statement"])
+	delete(fields, "Statement")
+	if len(raw) == 0 {
+		return fields, nil, nil
+	}
+	if raw[0] != '[' {
+		return fields, []json.RawMessage{raw}, nil
+	}
+	var statements []json.RawMessage
+	if err := json.Unmarshal(raw, &statements); err != nil {
+		return nil, nil, err
+	}
+	return fields, statements, nil
+}
+
+// encodeRawPolicy writes a bucket policy from the top-level elements and statements returned by
+// parseRawPolicy, copying them unchanged
+func encodeRawPolicy(fields map[string]json.RawMessage, statements []json.RawMessage) []byte {
+	if _, ok := fields["Version"]; !ok {
+		fields["Version"] = json.RawMessage(`"2012-10-17"`)
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != "Version" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys = append([]string{"Version"}, keys...)
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for _, key := range keys {
+		name, _ := json.Marshal(key)
+		fmt.Fprintf(&buf, "  %s: %s,\n", name, fields[key])
+	}
+	buf.WriteString("  \"Statement\": [")
+	for i, stmt := range statements {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n    ")
+		buf.Write(stmt)
+	}
+	buf.WriteString("\n  ]\n}")
+	return buf.Bytes()
+}
@@ -0,0 +1,221 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+)
+
+const (
+	// UserNamePrefix prefixes the names of users created for key authenticated BucketAccesses
+	UserNamePrefix = "cosi-user-"
+	// RoleNamePrefix prefixes the names of roles created for IAM authenticated BucketAccesses
+	RoleNamePrefix = "cosi-role-"
+	// rolePolicyName is the name of the inline policy granting a role access to its bucket
+	rolePolicyName = "cosi-bucket-access"
+)
+
+// RoleDocument is a trust or identity policy document attached to an IAM role
+type RoleDocument struct {
+	Version   string          `json:"Version"`
+	Statement []RoleStatement `json:"Statement"`
+}
+
+// RoleStatement is a single statement of a RoleDocument
+type RoleStatement struct {
+	Effect    string                       `json:"Effect"`
+	Principal map[string][]string          `json:"Principal,omitempty"`
+	Action    []string                     `json:"Action"`
+	Resource  []string                     `json:"Resource,omitempty"`
+	Condition map[string]map[string]string `json:"Condition,omitempty"`
+}
+
+// NewAssumeRoleTrustPolicy allows principal to assume the role with sts:AssumeRole
+func NewAssumeRoleTrustPolicy(principal string) RoleDocument {
+	return RoleDocument{
+		Version: version,
+		Statement: []RoleStatement{
+			{
+				Effect:    "Allow",
+				Principal: map[string][]string{"AWS": {principal}},
+				Action:    []string{"sts:AssumeRole"},
+			},
+		},
+	}
+}
+
+// NewWebIdentityTrustPolicy allows tokens issued by issuer for subject and audience to assume the
+// role with sts:AssumeRoleWithWebIdentity through the OIDC provider providerArn
+func NewWebIdentityTrustPolicy(providerArn, issuer, subject, audience string) RoleDocument {
+	issuer = strings.TrimPrefix(strings.TrimPrefix(issuer, "https://"), "http://")
+	conditions := map[string]string{
+		issuer + ":sub": subject,
+		issuer + ":aud": audience,
+	}
+	return RoleDocument{
+		Version: version,
+		Statement: []RoleStatement{
+			{
+				Effect:    "Allow",
+				Principal: map[string][]string{"Federated": {providerArn}},
+				Action:    []string{"sts:AssumeRoleWithWebIdentity"},
+				Condition: map[string]map[string]string{"StringEquals": conditions},
+			},
+		},
+	}
+}
+
+// NewBucketAccessRolePolicy allows the role to perform allowedActions on bucketName and its objects
+func NewBucketAccessRolePolicy(bucketName string, allowedActions []string) RoleDocument {
+	return RoleDocument{
+		Version: version,
+		Statement: []RoleStatement{
+			{
+				Effect: "Allow",
+				Action: allowedActions,
+				Resource: []string{
+					fmt.Sprintf(arnPrefixResource, bucketName),
+					fmt.Sprintf(arnPrefixResource, bucketName+"/*"),
+				},
+			},
+		},
+	}
+}
+
+// EnsureBucketRole creates the role with the given trust policy if it does not exist, or replaces
+// the trust policy of an existing role that differs from it, and (re)writes its inline policy so
+// that it grants allowedActions on the bucket
+func (s *S3Client) EnsureBucketRole(ctx context.Context, roleName string, trustPolicy RoleDocument, bucketName string, allowedActions []string) (*iam.Role, error) {
+	var role *iam.Role
+	getOutput, err := s.IAM.GetRole(roleName)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != iam.ErrCodeNoSuchEntityException {
+			klog.ErrorS(err, "Failed to get IAM role", "roleName", roleName)
+			return nil, status.Error(codes.Internal, "Failed to get IAM role")
+		}
+
+		trustJSON, err := json.Marshal(trustPolicy)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Failed to marshal role trust policy")
+		}
+
+		klog.InfoS("Role does not exist, attempting to create", "roleName", roleName)
+		createOutput, err := s.IAM.CreateRole(&iam.CreateRoleInput{
+			RoleName:                 aws.String(roleName),
+			AssumeRolePolicyDocument: aws.String(string(trustJSON)),
+			MaxSessionDuration:       aws.Int64(int64((12 * time.Hour).Seconds())),
+		})
+		s.recordAudit(ctx, audit.Event{Action: audit.ActionCreateRole, Role: roleName, PolicyAfter: trustJSON}, err)
+		if err != nil {
+			klog.ErrorS(err, "Failed to create IAM role", "roleName", roleName)
+			return nil, status.Error(codes.Internal, "Failed to create IAM role")
+		}
+		role = createOutput.Role
+	} else {
+		role = getOutput.Role
+		if err := s.updateTrustPolicy(ctx, role, trustPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	policyJSON, err := json.Marshal(NewBucketAccessRolePolicy(bucketName, allowedActions))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to marshal role policy")
+	}
+	_, err = s.IAM.PutRolePolicy(&iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String(rolePolicyName),
+		PolicyDocument: aws.String(string(policyJSON)),
+	})
+	s.recordAudit(ctx, audit.Event{Action: audit.ActionPutRolePolicy, Role: roleName, Bucket: bucketName, PolicyAfter: policyJSON}, err)
+	if err != nil {
+		klog.ErrorS(err, "Failed to put IAM role policy", "roleName", roleName)
+		return nil, status.Error(codes.Internal, "Failed to put IAM role policy")
+	}
+
+	klog.InfoS("Role grants bucket access", "roleName", roleName, "bucketName", bucketName, "actions", allowedActions)
+	return role, nil
+}
+
+// updateTrustPolicy replaces the trust policy of role with trustPolicy unless they are equal, e.g.
+// after the trusted principal changed or the access switched to web identity
+func (s *S3Client) updateTrustPolicy(ctx context.Context, role *iam.Role, trustPolicy RoleDocument) error {
+	roleName := aws.StringValue(role.RoleName)
+	trustJSON, err := json.Marshal(trustPolicy)
+	if err != nil {
+		return status.Error(codes.Internal, "Failed to marshal role trust policy")
+	}
+	current := aws.StringValue(role.AssumeRolePolicyDocument)
+	// IAM returns the document URL-encoded
+	if decoded, err := url.QueryUnescape(current); err == nil {
+		current = decoded
+	}
+	if equalDocuments(current, string(trustJSON)) {
+		return nil
+	}
+
+	klog.InfoS("Role trust policy changed, updating it", "roleName", roleName)
+	err = s.IAM.UpdateAssumeRolePolicy(roleName, string(trustJSON))
+	s.recordAudit(ctx, audit.Event{
+		Action:       audit.ActionUpdateTrustPolicy,
+		Role:         roleName,
+		PolicyBefore: json.RawMessage(current),
+		PolicyAfter:  trustJSON,
+	}, err)
+	if err != nil {
+		klog.ErrorS(err, "Failed to update IAM role trust policy", "roleName", roleName)
+		return status.Error(codes.Internal, "Failed to update IAM role trust policy")
+	}
+	role.AssumeRolePolicyDocument = aws.String(string(trustJSON))
+	return nil
+}
+
+// equalDocuments reports whether two JSON policy documents hold the same elements
+func equalDocuments(a, b string) bool {
+	var docA, docB interface{}
+	if json.Unmarshal([]byte(a), &docA) != nil || json.Unmarshal([]byte(b), &docB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(docA, docB)
+}
+
+// AssumeBucketRole returns temporary credentials for the role, valid for duration
+func (s *S3Client) AssumeBucketRole(roleArn, sessionName string, duration time.Duration) (*sts.Credentials, error) {
+	output, err := s.STS.AssumeRole(&sts.AssumeRoleInput{
+		RoleArn:         aws.String(roleArn),
+		RoleSessionName: aws.String(sessionName),
+		DurationSeconds: aws.Int64(int64(duration.Seconds())),
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to assume IAM role", "roleArn", roleArn)
+		return nil, status.Error(codes.Internal, "Failed to assume IAM role")
+	}
+	return output.Credentials, nil
+}
+
+// DeleteBucketRole deletes the role and its inline policies, recording the mutation in the audit log
+func (s *S3Client) DeleteBucketRole(ctx context.Context, roleName string) error {
+	err := s.IAM.DeleteRole(roleName)
+	s.recordAudit(ctx, audit.Event{Action: audit.ActionDeleteRole, Role: roleName}, err)
+	return err
+}
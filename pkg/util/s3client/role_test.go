@@ -0,0 +1,122 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+// newTestClient returns a client of the fake backend server for provider
+func newTestClient(t *testing.T, server *s3clienttest.Server, provider string) *S3Client {
+	t.Helper()
+	params, err := FetchParameters(server.SecretData(provider))
+	if err != nil {
+		t.Fatalf("FetchParameters: %v", err)
+	}
+	client, err := NewS3Client(params, false)
+	if err != nil {
+		t.Fatalf("NewS3Client: %v", err)
+	}
+	return client
+}
+
+func TestEnsureBucketRoleAndAssume(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	client := newTestClient(t, server, ProviderCephRGW)
+
+	trustPolicy := NewAssumeRoleTrustPolicy("arn:aws:iam::" + s3clienttest.AccountID + ":root")
+	role, err := client.EnsureBucketRole(context.Background(), "cosi-role-ba-1", trustPolicy, "bucket1", []string{"s3:GetObject"})
+	if err != nil {
+		t.Fatalf("EnsureBucketRole: %v", err)
+	}
+	if got, want := aws.StringValue(role.Arn), s3clienttest.RoleARN("cosi-role-ba-1"); got != want {
+		t.Errorf("role ARN = %q, want %q", got, want)
+	}
+
+	stored, ok := server.Role("cosi-role-ba-1")
+	if !ok {
+		t.Fatal("role was not created")
+	}
+	var trust RoleDocument
+	if err := json.Unmarshal([]byte(stored.TrustPolicy), &trust); err != nil {
+		t.Fatalf("trust policy: %v", err)
+	}
+	if got := trust.Statement[0].Principal["AWS"]; len(got) != 1 || got[0] != "arn:aws:iam::"+s3clienttest.AccountID+":root" {
+		t.Errorf("trust principal = %v", got)
+	}
+	var policy RoleDocument
+	if err := json.Unmarshal([]byte(stored.Policies[rolePolicyName]), &policy); err != nil {
+		t.Fatalf("role policy: %v", err)
+	}
+	if got := policy.Statement[0].Resource; len(got) != 2 || got[0] != "arn:aws:s3:::bucket1" || got[1] != "arn:aws:s3:::bucket1/*" {
+		t.Errorf("role policy resources = %v", got)
+	}
+
+	// A second grant rewrites the inline policy of the existing role
+	if _, err := client.EnsureBucketRole(context.Background(), "cosi-role-ba-1", trustPolicy, "bucket1", []string{"s3:*"}); err != nil {
+		t.Fatalf("EnsureBucketRole again: %v", err)
+	}
+	if n := server.Count("iam:CreateRole"); n != 1 {
+		t.Errorf("CreateRole called %d times, want 1", n)
+	}
+
+	creds, err := client.AssumeBucketRole(aws.StringValue(role.Arn), "ba-1", time.Hour)
+	if err != nil {
+		t.Fatalf("AssumeBucketRole: %v", err)
+	}
+	if aws.StringValue(creds.SessionToken) == "" {
+		t.Error("assumed role credentials have no session token")
+	}
+	if until := time.Until(aws.TimeValue(creds.Expiration)); until < 59*time.Minute || until > time.Hour+time.Minute {
+		t.Errorf("credentials expire in %s, want about an hour", until)
+	}
+}
+
+func TestEnsureBucketRoleUpdatesTrustPolicy(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	client := newTestClient(t, server, ProviderCephRGW)
+	ctx := context.Background()
+
+	rootTrust := NewAssumeRoleTrustPolicy("arn:aws:iam::" + s3clienttest.AccountID + ":root")
+	if _, err := client.EnsureBucketRole(ctx, "cosi-role-ba-1", rootTrust, "bucket1", []string{"s3:GetObject"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.EnsureBucketRole(ctx, "cosi-role-ba-1", rootTrust, "bucket1", []string{"s3:GetObject"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.Count("iam:UpdateAssumeRolePolicy"); n != 0 {
+		t.Errorf("unchanged trust policy was updated %d times", n)
+	}
+
+	// The access switched to web identity
+	webTrust := NewWebIdentityTrustPolicy("arn:aws:iam::"+s3clienttest.AccountID+":oidc-provider/oidc.example.com",
+		"https://oidc.example.com", "system:serviceaccount:team-a:app", "sts.amazonaws.com")
+	if _, err := client.EnsureBucketRole(ctx, "cosi-role-ba-1", webTrust, "bucket1", []string{"s3:GetObject"}); err != nil {
+		t.Fatalf("EnsureBucketRole: %v", err)
+	}
+	if n := server.Count("iam:UpdateAssumeRolePolicy"); n != 1 {
+		t.Errorf("trust policy was updated %d times, want 1", n)
+	}
+	stored, _ := server.Role("cosi-role-ba-1")
+	var trust RoleDocument
+	if err := json.Unmarshal([]byte(stored.TrustPolicy), &trust); err != nil {
+		t.Fatalf("trust policy: %v", err)
+	}
+	if got := trust.Statement[0].Principal["Federated"]; len(got) != 1 {
+		t.Errorf("trust policy principal = %v, want the OIDC provider", trust.Statement[0].Principal)
+	}
+	if n := server.Count("iam:CreateRole"); n != 1 {
+		t.Errorf("CreateRole called %d times, want 1", n)
+	}
+}
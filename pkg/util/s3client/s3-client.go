@@ -25,12 +25,9 @@ package s3client
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
-	"fmt"
-	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,13 +35,15 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/logging"
 )
 
 const (
@@ -54,8 +53,15 @@ const (
 
 // S3Client wraps the S3 and IAM APIs
 type S3Client struct {
-	S3  s3iface.S3API
-	IAM IAMClientInterface
+	S3         s3iface.S3API
+	IAM        IAMClientInterface
+	STS        STSClientInterface
+	Admin      AdminClientInterface
+	Endpoint   string
+	Profile    *ProviderProfile
+	Principals *PrincipalFormatter
+	// DryRun is set on clients that log mutations instead of performing them
+	DryRun bool
 }
 
 func NewS3Client(params *S3ClientParams, debug bool) (*S3Client, error) {
@@ -64,25 +70,11 @@ func NewS3Client(params *S3ClientParams, debug bool) (*S3Client, error) {
 		logLevel = aws.LogDebug
 	}
 
-	// Create base HTTP client
-	client := http.Client{
-		Timeout: HttpTimeOut,
-	}
-
-	// Handle TLS configuration
-	klog.V(5).InfoS("Creating S3 client with endpoint", "endpoint", params.Endpoint)
-	tlsEnabled := false
-	insecure := true
-	if strings.HasPrefix(params.Endpoint, "https") {
-		klog.V(5).InfoS("Using secure endpoint")
-		insecure = false
-		if len(params.TlsCert) > 0 {
-			klog.V(5).InfoS("Using TLS certificate")
-			tlsEnabled = true
-			client.Transport = buildTransportTLS(params.TlsCert, insecure)
-		}
-	} else {
-		klog.V(5).InfoS("Using insecure endpoint", "endpoint", params.Endpoint)
+	endpoint := params.GetFullEndpoint()
+	klog.V(5).InfoS("Creating S3 client with endpoint", "endpoint", endpoint)
+	client, err := newHTTPClient(params, endpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create S3 session with S3 endpoint
@@ -90,16 +82,18 @@ func NewS3Client(params *S3ClientParams, debug bool) (*S3Client, error) {
 		aws.NewConfig().
 			WithRegion(params.Region).
 			WithCredentials(credentials.NewStaticCredentials(params.AccessKey, params.SecretKey, "")).
-			WithEndpoint(params.GetFullEndpoint()).
-			WithS3ForcePathStyle(true).
+			WithEndpoint(endpoint).
+			WithS3ForcePathStyle(params.Profile.PathStyle).
 			WithMaxRetries(5).
-			WithDisableSSL(!tlsEnabled).
-			WithHTTPClient(&client).
-			WithLogLevel(logLevel),
+			WithDisableSSL(!isSecureEndpoint(endpoint)).
+			WithHTTPClient(client).
+			WithLogLevel(logLevel).
+			WithLogger(logging.AWSLogger()),
 	)
 	if err != nil {
 		return nil, err
 	}
+	instrumentSession(s3Session, serviceS3)
 	s3Svc := s3.New(s3Session)
 
 	// Create IAM client with IAM endpoint
@@ -108,9 +102,30 @@ func NewS3Client(params *S3ClientParams, debug bool) (*S3Client, error) {
 		return nil, err
 	}
 
+	// Create STS client for temporary credentials
+	stsClient, err := NewSTSClient(params, debug)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create admin client for provider specific operations, nil if the provider has none
+	adminClient, err := NewAdminClient(params)
+	if err != nil {
+		return nil, err
+	}
+
 	return &S3Client{
-		S3:  s3Svc,
-		IAM: iamClient,
+		S3:       s3Svc,
+		IAM:      iamClient,
+		STS:      stsClient,
+		Admin:    adminClient,
+		Endpoint: params.GetFullEndpoint(),
+		Profile:  params.Profile,
+		Principals: &PrincipalFormatter{
+			Format:    params.PrincipalFormat,
+			AccountID: params.AccountID,
+			Tenant:    params.Tenant,
+		},
 	}, nil
 }
 
@@ -254,52 +269,6 @@ func (s *S3Client) GetBucketPolicy(bucket string) (*BucketPolicy, error) {
 	return policy, nil
 }
 
-func buildTransportTLS(tlsCert []byte, insecure bool) *http.Transport {
-	//nolint:gosec // is enabled only for testing
-	tlsConfig := &tls.Config{
-		MinVersion:         tls.VersionTLS12,
-		InsecureSkipVerify: insecure,
-	}
-
-	if len(tlsCert) > 0 {
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(tlsCert)
-		tlsConfig.RootCAs = caCertPool
-	}
-
-	return &http.Transport{
-		TLSClientConfig: tlsConfig,
-	}
-}
-
-// InitializeClients creates and returns an S3 client using the provided parameters
-func InitializeClients(ctx context.Context, clientset *kubernetes.Clientset, parameters map[string]string) (*S3Client, error) {
-	klog.V(5).Infof("Initializing clients %v", parameters)
-
-	accountSecretName, namespace, err := FetchSecretNameAndNamespace(parameters)
-	if err != nil {
-		return nil, err
-	}
-
-	accountSecret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, accountSecretName, metav1.GetOptions{})
-	if err != nil {
-		klog.ErrorS(err, "Failed to get object store user secret")
-		return nil, status.Error(codes.Internal, "Failed to get object store user secret")
-	}
-
-	s3Params, err := FetchParameters(accountSecret.Data)
-	if err != nil {
-		return nil, err
-	}
-
-	s3Client, err := NewS3Client(s3Params, false)
-	if err != nil {
-		klog.ErrorS(err, "Failed to create s3 client")
-		return nil, status.Error(codes.Internal, "Failed to create s3 client")
-	}
-	return s3Client, nil
-}
-
 // FetchSecretNameAndNamespace retrieves the secret name and namespace from the parameters
 func FetchSecretNameAndNamespace(parameters map[string]string) (string, string, error) {
 	secretName := parameters["accountSecret"]
@@ -319,20 +288,71 @@ func FetchParameters(secretData map[string][]byte) (*S3ClientParams, error) {
 	endPoint := string(secretData["Endpoint"])
 	s3Port := string(secretData["S3Port"])
 	iamPort := string(secretData["IAMPort"])
+	iamEndpoint := string(secretData["IAMEndpoint"])
+	stsEndpoint := string(secretData["STSEndpoint"])
 	accountName := string(secretData["AccountName"])
 	accessKey := string(secretData["AccessKey"])
 	secretKey := string(secretData["SecretKey"])
 	region := string(secretData["Region"])
+	provider := string(secretData["Provider"])
+	principalFormat := string(secretData["PrincipalFormat"])
+	accountID := string(secretData["AccountID"])
+	tenant := string(secretData["Tenant"])
 
 	// Handle TlsCert - use the raw bytes, will be empty slice if not present
 	tlsCert := secretData["TlsCert"]
+	tlsClientCert := secretData["TlsClientCert"]
+	tlsClientKey := secretData["TlsClientKey"]
+	insecureSkipVerify := false
+	if value := string(secretData["InsecureSkipVerify"]); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "insecureSkipVerify must be true or false")
+		}
+		insecureSkipVerify = parsed
+	}
+	if insecureSkipVerify {
+		klog.Warning("TLS certificate verification is disabled for the account endpoint")
+	}
 
 	if endPoint == "" || accessKey == "" || secretKey == "" {
 		return nil, status.Error(codes.InvalidArgument, "endpoint, accessKeyID and secretKey are required")
 	}
 
-	if s3Port == "" || iamPort == "" {
-		return nil, status.Error(codes.InvalidArgument, "s3Port and iamPort are required")
+	profile, err := GetProviderProfile(provider)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if value := string(secretData["DenyAllBaseline"]); value != "" {
+		denyAll, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "denyAllBaseline must be true or false")
+		}
+		profile.DenyAllBaseline = denyAll
+	}
+	format := profile.PrincipalFormat
+	if principalFormat != "" {
+		if format, err = ParsePrincipalFormat(principalFormat); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if iamEndpoint == "" {
+		iamEndpoint = profile.IAMEndpoint
+	}
+	if stsEndpoint == "" {
+		stsEndpoint = profile.STSEndpoint
+	}
+	if iamPort == "" && iamEndpoint == "" {
+		iamPort = profile.defaultIAMPort(endPoint, s3Port)
+	}
+
+	// Ports are only needed to derive URLs that were not given in full. A declared provider
+	// may rely on the default port of the protocol.
+	if s3Port == "" && !hasPort(endPoint) && profile.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "s3Port is required unless endpoint includes a port")
+	}
+	if iamPort == "" && iamEndpoint == "" && !hasPort(endPoint) && profile.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "iamPort is required unless iamEndpoint is set or endpoint includes a port")
 	}
 
 	if accountName == "" {
@@ -343,6 +363,20 @@ func FetchParameters(secretData map[string][]byte) (*S3ClientParams, error) {
 	if !strings.HasPrefix(endPoint, "http://") && !strings.HasPrefix(endPoint, "https://") {
 		return nil, status.Error(codes.InvalidArgument, "endpoint must include http:// or https:// protocol")
 	}
+	for key, value := range map[string]string{"iamEndpoint": iamEndpoint, "stsEndpoint": stsEndpoint} {
+		if value != "" && !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+			return nil, status.Errorf(codes.InvalidArgument, "%s must include http:// or https:// protocol", key)
+		}
+	}
+
+	quotas, err := parseQuotas(secretData)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if (len(tlsClientCert) > 0) != (len(tlsClientKey) > 0) {
+		return nil, status.Error(codes.InvalidArgument, "tlsClientCert and tlsClientKey must be set together")
+	}
 
 	// AWS requires a region
 	if region == "" {
@@ -350,20 +384,45 @@ func FetchParameters(secretData map[string][]byte) (*S3ClientParams, error) {
 		region = rgwRegion
 	}
 
-	return &S3ClientParams{
+	params := &S3ClientParams{
 		Endpoint:    endPoint,
 		S3Port:      s3Port,
 		IAMPort:     iamPort,
+		IAMEndpoint: iamEndpoint,
+		STSEndpoint: stsEndpoint,
 		AccountName: accountName,
 		AccessKey:   accessKey,
 		SecretKey:   secretKey,
 		TlsCert:     tlsCert,
 		Region:      region,
-	}, nil
+		Provider:    provider,
+		Profile:     profile,
+
+		PrincipalFormat: format,
+		AccountID:       accountID,
+		Tenant:          tenant,
+
+		Quotas: quotas,
+
+		TlsClientCert:      tlsClientCert,
+		TlsClientKey:       tlsClientKey,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	// Reject unusable TLS material up front instead of failing every request
+	if _, err := buildTransportTLS(params); err != nil {
+		klog.ErrorS(err, "Invalid TLS configuration in account credentials")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Never let the account credentials reach the logs, even via SDK request dumps. The client
+	// cache unregisters them once the client built from params is dropped.
+	logging.RegisterSecret(accessKey, secretKey, string(tlsClientKey))
+	return params, nil
 }
 
 // AddUserToBucketPolicy adds a user to a bucket's policy with the specified access mode
-func (s *S3Client) AddUserToBucketPolicy(bucketName, userName string, allowedActions []string) error {
+func (s *S3Client) AddUserToBucketPolicy(ctx context.Context, bucketName, userName string, allowedActions []string) error {
 	klog.InfoS("Attempting to add user to bucket policy",
 		"bucketName", bucketName,
 		"username", userName,
@@ -396,74 +455,69 @@ func (s *S3Client) AddUserToBucketPolicy(bucketName, userName string, allowedAct
 	}
 
 	// Create new statement for the user
-	newStatement := RawPolicyStatement{
-		Effect: "Allow",
-		Principal: map[string][]string{
-			"AWS": {*userOutput.User.UserId},
-		},
-		Action: allowedActions,
-		Resource: []string{
-			fmt.Sprintf("arn:aws:s3:::%s", bucketName),
-			fmt.Sprintf("arn:aws:s3:::%s/*", bucketName),
-		},
+	principal := s.principals().Principal(userOutput.User)
+	newStatement, err := json.MarshalIndent(userStatement(bucketName, userName, principal, allowedActions), "    ", "  ")
+	if err != nil {
+		klog.ErrorS(err, "Failed to marshal policy statement",
+			"bucketName", bucketName)
+		return err
 	}
 
-	var policyJSON []byte
+	fields := map[string]json.RawMessage{}
+	var statements []json.RawMessage
 	if policy != nil {
 		// Parse existing policy
 		klog.V(5).InfoS("Parsing existing policy", "bucketName", bucketName)
-		var rawPolicy RawBucketPolicy
-		err = json.Unmarshal([]byte(*policy.Policy), &rawPolicy)
+		fields, statements, err = parseRawPolicy(aws.StringValue(policy.Policy))
 		if err != nil {
 			klog.ErrorS(err, "Failed to unmarshal existing policy",
 				"bucketName", bucketName,
-				"policy", *policy.Policy)
+				"policy", logging.RedactPolicy(*policy.Policy))
 			return err
 		}
 
 		klog.V(5).InfoS("Existing policy",
 			"bucketName", bucketName,
-			"statements", len(rawPolicy.Statement))
-
-		// Check if user already has access
-		for _, stmt := range rawPolicy.Statement {
-			if principal, ok := stmt.Principal.(map[string]interface{}); ok {
-				if users, ok := principal["AWS"].([]interface{}); ok {
-					for _, u := range users {
-						if u.(string) == userName {
-							klog.InfoS("User already has access to bucket",
-								"bucketName", bucketName,
-								"username", userName)
-							return nil
-						}
-					}
-				}
-			}
+			"statements", len(statements))
+	}
+
+	// The statement of the user is replaced when it grants other actions, e.g. after the policy
+	// decision point narrowed them. The deny-all baseline only protects buckets nobody was granted
+	// access to yet. Statements the driver did not write are kept as they are.
+	var kept []json.RawMessage
+	var current []policyStatement
+	for _, raw := range statements {
+		var stmt policyStatement
+		_ = json.Unmarshal(raw, &stmt)
+		if s.driverStatementUser(stmt, bucketName, []*iam.User{userOutput.User}) != nil {
+			current = append(current, stmt)
+			continue
 		}
-
-		// Append new statement
-		klog.V(5).InfoS("appending new statement to existing policy", "bucketName", bucketName)
-		rawPolicy.Statement = append(rawPolicy.Statement, newStatement)
-		policyJSON, err = json.MarshalIndent(rawPolicy, "", "  ")
-	} else {
-		// Create new policy with just this statement
-		klog.V(5).InfoS("creating new policy", "bucketName", bucketName)
-		rawPolicy := RawBucketPolicy{
-			Version:   "2012-10-17",
-			Statement: []RawPolicyStatement{newStatement},
+		if !isRawDenyAll(stmt) {
+			kept = append(kept, raw)
 		}
-		policyJSON, err = json.MarshalIndent(rawPolicy, "", "  ")
 	}
+	if len(current) == 1 && statementMatches(current[0], bucketName, principal, allowedActions) {
+		klog.InfoS("User already has access to bucket",
+			"bucketName", bucketName,
+			"username", userName)
+		return nil
+	}
+	if len(current) > 0 {
+		klog.InfoS("Replacing statement of user with different actions",
+			"bucketName", bucketName,
+			"username", userName)
+	}
+	policyJSON := encodeRawPolicy(fields, append(kept, newStatement))
 
-	if err != nil {
-		klog.ErrorS(err, "Failed to marshal policy",
-			"bucketName", bucketName)
-		return err
+	if err := s.profile().checkPolicySize(policyJSON); err != nil {
+		klog.ErrorS(err, "Bucket policy too large", "bucketName", bucketName)
+		return status.Error(codes.ResourceExhausted, err.Error())
 	}
 
 	klog.V(5).InfoS("Setting bucket policy",
 		"bucketName", bucketName,
-		"policy", string(policyJSON))
+		"policy", logging.RedactPolicy(string(policyJSON)))
 
 	// Put the updated policy
 	policyStr := string(policyJSON)
@@ -471,6 +525,11 @@ func (s *S3Client) AddUserToBucketPolicy(bucketName, userName string, allowedAct
 		Bucket: aws.String(bucketName),
 		Policy: &policyStr,
 	})
+	previousPolicy := ""
+	if policy != nil {
+		previousPolicy = aws.StringValue(policy.Policy)
+	}
+	s.recordPolicyAudit(ctx, audit.ActionPutBucketPolicy, bucketName, userName, previousPolicy, policyStr, err)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			klog.ErrorS(err, "Failed to set bucket policy",
@@ -492,7 +551,7 @@ func (s *S3Client) AddUserToBucketPolicy(bucketName, userName string, allowedAct
 }
 
 // RemoveUserFromBucketPolicy removes a user from a bucket's policy
-func (s *S3Client) RemoveUserFromBucketPolicy(bucketName, userName string) error {
+func (s *S3Client) RemoveUserFromBucketPolicy(ctx context.Context, bucketName, userName string) error {
 	klog.InfoS("Attempting to remove user from bucket policy",
 		"bucketName", bucketName,
 		"username", userName)
@@ -515,11 +574,10 @@ func (s *S3Client) RemoveUserFromBucketPolicy(bucketName, userName string) error
 
 	klog.InfoS("current bucket policy",
 		"bucketName", bucketName,
-		"policy", *policy.Policy)
+		"policy", logging.RedactPolicy(*policy.Policy))
 
-	// Parse the policy JSON
-	var rawPolicy RawBucketPolicy
-	err = json.Unmarshal([]byte(*policy.Policy), &rawPolicy)
+	// Parse the policy JSON, keeping every statement raw
+	fields, statements, err := parseRawPolicy(*policy.Policy)
 	if err != nil {
 		klog.ErrorS(err, "failed to unmarshal bucket policy",
 			"bucketName", bucketName)
@@ -528,7 +586,7 @@ func (s *S3Client) RemoveUserFromBucketPolicy(bucketName, userName string) error
 
 	klog.InfoS("parsed bucket policy",
 		"bucketName", bucketName,
-		"statements", len(rawPolicy.Statement))
+		"statements", len(statements))
 
 	// Get user ID
 	userOutput, err := s.IAM.GetUser(userName)
@@ -538,43 +596,48 @@ func (s *S3Client) RemoveUserFromBucketPolicy(bucketName, userName string) error
 			"username", userName)
 		return err
 	}
-	klog.V(5).InfoS("user ID",
-		"bucketName", bucketName,
-		"username", userName,
-		"userId", *userOutput.User.UserId)
-
-	// Filter out statements that grant access to this user
-	var newStatements []RawPolicyStatement
-	for i, stmt := range rawPolicy.Statement {
-		// Skip statements that grant access to this user
-		if principal, ok := stmt.Principal.(map[string]interface{}); ok {
-			if users, ok := principal["AWS"].([]interface{}); ok {
-				// Check if this statement affects our user
-				affectsUser := false
-				for _, u := range users {
-					if u.(string) == *userOutput.User.UserId {
-						affectsUser = true
-						break
-					}
-				}
-				if affectsUser {
-					klog.InfoS("removing statement that affects user",
-						"bucketName", bucketName,
-						"username", userName,
-						"userId", *userOutput.User.UserId,
-						"statementIndex", i)
-					continue // Skip this statement
-				}
-			}
+
+	// Filter out the statements the driver wrote for this user. Statements an admin wrote are
+	// kept as they are, even when they name the user: dropping a Deny or a statement shared with
+	// other principals could widen or break access.
+	var newStatements []json.RawMessage
+	for i, raw := range statements {
+		var stmt policyStatement
+		_ = json.Unmarshal(raw, &stmt)
+		if s.driverStatementUser(stmt, bucketName, []*iam.User{userOutput.User}) != nil {
+			klog.InfoS("removing statement of user",
+				"bucketName", bucketName,
+				"username", userName,
+				"statementIndex", i)
+			continue
 		}
-		newStatements = append(newStatements, stmt)
+		newStatements = append(newStatements, raw)
 	}
 
 	klog.InfoS("filtered policy statements",
 		"bucketName", bucketName,
-		"originalCount", len(rawPolicy.Statement),
+		"originalCount", len(statements),
 		"newCount", len(newStatements))
 
+	if len(newStatements) == len(statements) {
+		klog.InfoS("no statements of user, leaving policy unchanged",
+			"bucketName", bucketName,
+			"username", userName)
+		return nil
+	}
+
+	// If we removed all statements, restore the deny-all baseline where the provider supports it
+	if len(newStatements) == 0 && s.profile().DenyAllBaseline {
+		klog.InfoS("all statements removed, restoring deny-all baseline",
+			"bucketName", bucketName)
+		if err := s.PutDenyAllBaseline(ctx, bucketName, *policy.Policy); err != nil {
+			klog.ErrorS(err, "failed to restore deny-all baseline",
+				"bucketName", bucketName)
+			return err
+		}
+		return nil
+	}
+
 	// If we removed all statements, delete the entire policy
 	if len(newStatements) == 0 {
 		klog.InfoS("all statements removed, deleting entire policy",
@@ -582,6 +645,7 @@ func (s *S3Client) RemoveUserFromBucketPolicy(bucketName, userName string) error
 		_, err = s.S3.DeleteBucketPolicy(&s3.DeleteBucketPolicyInput{
 			Bucket: aws.String(bucketName),
 		})
+		s.recordPolicyAudit(ctx, audit.ActionDeleteBucketPolicy, bucketName, userName, *policy.Policy, "", err)
 		if err != nil {
 			klog.ErrorS(err, "failed to delete bucket policy",
 				"bucketName", bucketName)
@@ -592,18 +656,12 @@ func (s *S3Client) RemoveUserFromBucketPolicy(bucketName, userName string) error
 		return nil
 	}
 
-	// Update the policy with the filtered statements
-	rawPolicy.Statement = newStatements
-	policyJSON, err := json.MarshalIndent(rawPolicy, "", "  ")
-	if err != nil {
-		klog.ErrorS(err, "failed to marshal updated policy",
-			"bucketName", bucketName)
-		return err
-	}
+	// Update the policy with the remaining statements
+	policyJSON := encodeRawPolicy(fields, newStatements)
 
 	klog.InfoS("updated policy content",
 		"bucketName", bucketName,
-		"policy", string(policyJSON))
+		"policy", logging.RedactPolicy(string(policyJSON)))
 
 	// Put the updated policy back
 	policyStr := string(policyJSON)
@@ -611,6 +669,7 @@ func (s *S3Client) RemoveUserFromBucketPolicy(bucketName, userName string) error
 		Bucket: aws.String(bucketName),
 		Policy: &policyStr,
 	})
+	s.recordPolicyAudit(ctx, audit.ActionPutBucketPolicy, bucketName, userName, *policy.Policy, policyStr, err)
 	if err != nil {
 		klog.ErrorS(err, "failed to update bucket policy",
 			"bucketName", bucketName)
@@ -0,0 +1,630 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+// Package s3clienttest provides an in-memory S3, IAM and STS backend for tests
+package s3clienttest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccountID is the account the fake backend issues ARNs in
+const AccountID = "123456789012"
+
+// Server is a fake S3-compatible backend serving the S3 API path-style and the IAM and STS query
+// APIs on the same address, as Ceph RGW and MinIO do
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	buckets  map[string]*Bucket
+	users    map[string]*User
+	roles    map[string]*Role
+	taken    map[string]bool // bucket names owned by other accounts
+	requests []string
+	sessions int
+}
+
+// Bucket is a bucket held by the fake backend
+type Bucket struct {
+	Policy string
+	Tags   map[string]string
+}
+
+// User is an IAM user held by the fake backend
+type User struct {
+	ID         string
+	Created    time.Time
+	Tags       map[string]string
+	AccessKeys []string
+}
+
+// Role is an IAM role held by the fake backend
+type Role struct {
+	TrustPolicy string
+	Policies    map[string]string
+}
+
+// NewServer starts a fake backend. It is closed when the test ends.
+func NewServer(t interface{ Cleanup(func()) }) *Server {
+	s := &Server{
+		buckets: map[string]*Bucket{},
+		users:   map[string]*User{},
+		roles:   map[string]*Role{},
+		taken:   map[string]bool{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SecretData returns the account Secret data of the fake backend for provider
+func (s *Server) SecretData(provider string) map[string][]byte {
+	return map[string][]byte{
+		"Endpoint":    []byte(s.URL),
+		"AccountName": []byte("fake-account"),
+		"AccessKey":   []byte("AKIAFAKEACCOUNT"),
+		"SecretKey":   []byte("fake-account-secret-key"),
+		"Region":      []byte("us-east-1"),
+		"Provider":    []byte(provider),
+		"AccountID":   []byte(AccountID),
+	}
+}
+
+// Requests returns the operations served so far, e.g. "iam:CreateUser" or "s3:PutBucketPolicy"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Count returns how often operation was served
+func (s *Server) Count(operation string) int {
+	n := 0
+	for _, request := range s.Requests() {
+		if request == operation {
+			n++
+		}
+	}
+	return n
+}
+
+// AddBucket creates a bucket with policy, which may be empty
+func (s *Server) AddBucket(name, policy string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[name] = &Bucket{Policy: policy, Tags: map[string]string{}}
+}
+
+// AddForeignBucket marks name as taken by a bucket of another account
+func (s *Server) AddForeignBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taken[name] = true
+}
+
+// Bucket returns a copy of the named bucket
+func (s *Server) Bucket(name string) (Bucket, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, ok := s.buckets[name]
+	if !ok {
+		return Bucket{}, false
+	}
+	return Bucket{Policy: bucket.Policy, Tags: copyTags(bucket.Tags)}, true
+}
+
+// AddUser creates a user created at created with tags
+func (s *Server) AddUser(name string, created time.Time, tags map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[name] = &User{ID: "AIDA" + strings.ToUpper(name), Created: created, Tags: copyTags(tags)}
+}
+
+// User returns a copy of the named user
+func (s *Server) User(name string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[name]
+	if !ok {
+		return User{}, false
+	}
+	return User{ID: user.ID, Created: user.Created, Tags: copyTags(user.Tags), AccessKeys: append([]string(nil), user.AccessKeys...)}, true
+}
+
+// AddRole creates a role with trustPolicy
+func (s *Server) AddRole(name, trustPolicy string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles[name] = &Role{TrustPolicy: trustPolicy, Policies: map[string]string{}}
+}
+
+// Role returns a copy of the named role
+func (s *Server) Role(name string) (Role, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role, ok := s.roles[name]
+	if !ok {
+		return Role{}, false
+	}
+	return Role{TrustPolicy: role.TrustPolicy, Policies: copyTags(role.Policies)}, true
+}
+
+// UserARN returns the ARN of the named user
+func UserARN(name string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:user/%s", AccountID, name)
+}
+
+// RoleARN returns the ARN of the named role
+func RoleARN(name string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:role/%s", AccountID, name)
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == http.MethodPost && r.URL.Path == "/" {
+		if err := r.ParseForm(); err != nil {
+			writeQueryError(w, http.StatusBadRequest, "InvalidInput", err.Error())
+			return
+		}
+		action := r.PostForm.Get("Action")
+		if action == "AssumeRole" {
+			s.requests = append(s.requests, "sts:"+action)
+		} else {
+			s.requests = append(s.requests, "iam:"+action)
+		}
+		s.serveQuery(w, action, r.PostForm)
+		return
+	}
+	s.serveS3(w, r)
+}
+
+// iamUser is the XML encoding of an IAM user
+type iamUser struct {
+	UserName   string
+	UserId     string
+	Arn        string
+	Path       string
+	CreateDate string
+}
+
+type iamRole struct {
+	RoleName                 string
+	RoleId                   string
+	Arn                      string
+	Path                     string
+	CreateDate               string
+	AssumeRolePolicyDocument string
+}
+
+type iamTag struct {
+	Key   string
+	Value string
+}
+
+func (s *Server) encodeUser(name string, user *User) iamUser {
+	return iamUser{
+		UserName:   name,
+		UserId:     user.ID,
+		Arn:        UserARN(name),
+		Path:       "/",
+		CreateDate: user.Created.UTC().Format(time.RFC3339),
+	}
+}
+
+func (s *Server) serveQuery(w http.ResponseWriter, action string, form map[string][]string) {
+	get := func(key string) string {
+		if values := form[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	noSuchEntity := func(name string) {
+		writeQueryError(w, http.StatusNotFound, "NoSuchEntity", fmt.Sprintf("%s cannot be found", name))
+	}
+
+	switch action {
+	case "GetUser":
+		user, ok := s.users[get("UserName")]
+		if !ok {
+			noSuchEntity(get("UserName"))
+			return
+		}
+		writeQueryResult(w, action, struct{ User iamUser }{s.encodeUser(get("UserName"), user)})
+	case "CreateUser":
+		name := get("UserName")
+		if _, ok := s.users[name]; ok {
+			writeQueryError(w, http.StatusConflict, "EntityAlreadyExists", name+" already exists")
+			return
+		}
+		user := &User{ID: "AIDA" + strings.ToUpper(name), Created: time.Now(), Tags: map[string]string{}}
+		s.users[name] = user
+		writeQueryResult(w, action, struct{ User iamUser }{s.encodeUser(name, user)})
+	case "DeleteUser":
+		if _, ok := s.users[get("UserName")]; !ok {
+			noSuchEntity(get("UserName"))
+			return
+		}
+		delete(s.users, get("UserName"))
+		writeQueryResult(w, action, struct{}{})
+	case "ListUsers":
+		type usersResult struct {
+			Users       []iamUser `xml:"Users>member"`
+			IsTruncated bool
+		}
+		result := usersResult{}
+		for _, name := range sortedKeys(s.users) {
+			result.Users = append(result.Users, s.encodeUser(name, s.users[name]))
+		}
+		writeQueryResult(w, action, result)
+	case "CreateAccessKey":
+		user, ok := s.users[get("UserName")]
+		if !ok {
+			noSuchEntity(get("UserName"))
+			return
+		}
+		keyID := fmt.Sprintf("AKIAFAKE%08d", len(user.AccessKeys)+1)
+		user.AccessKeys = append(user.AccessKeys, keyID)
+		type accessKey struct {
+			UserName        string
+			AccessKeyId     string
+			SecretAccessKey string
+			Status          string
+			CreateDate      string
+		}
+		writeQueryResult(w, action, struct{ AccessKey accessKey }{accessKey{
+			UserName:        get("UserName"),
+			AccessKeyId:     keyID,
+			SecretAccessKey: "fake-secret-" + keyID,
+			Status:          "Active",
+			CreateDate:      time.Now().UTC().Format(time.RFC3339),
+		}})
+	case "ListAccessKeys":
+		user, ok := s.users[get("UserName")]
+		if !ok {
+			noSuchEntity(get("UserName"))
+			return
+		}
+		type accessKeyMetadata struct {
+			UserName    string
+			AccessKeyId string
+			Status      string
+		}
+		type keysResult struct {
+			AccessKeyMetadata []accessKeyMetadata `xml:"AccessKeyMetadata>member"`
+			IsTruncated       bool
+		}
+		result := keysResult{}
+		for _, keyID := range user.AccessKeys {
+			result.AccessKeyMetadata = append(result.AccessKeyMetadata, accessKeyMetadata{get("UserName"), keyID, "Active"})
+		}
+		writeQueryResult(w, action, result)
+	case "DeleteAccessKey":
+		if user, ok := s.users[get("UserName")]; ok {
+			for i, keyID := range user.AccessKeys {
+				if keyID == get("AccessKeyId") {
+					user.AccessKeys = append(user.AccessKeys[:i], user.AccessKeys[i+1:]...)
+					break
+				}
+			}
+		}
+		writeQueryResult(w, action, struct{}{})
+	case "TagUser":
+		user, ok := s.users[get("UserName")]
+		if !ok {
+			noSuchEntity(get("UserName"))
+			return
+		}
+		for i := 1; get(fmt.Sprintf("Tags.member.%d.Key", i)) != ""; i++ {
+			user.Tags[get(fmt.Sprintf("Tags.member.%d.Key", i))] = get(fmt.Sprintf("Tags.member.%d.Value", i))
+		}
+		writeQueryResult(w, action, struct{}{})
+	case "ListUserTags":
+		user, ok := s.users[get("UserName")]
+		if !ok {
+			noSuchEntity(get("UserName"))
+			return
+		}
+		type tagsResult struct {
+			Tags        []iamTag `xml:"Tags>member"`
+			IsTruncated bool
+		}
+		result := tagsResult{}
+		for _, key := range sortedKeys(user.Tags) {
+			result.Tags = append(result.Tags, iamTag{key, user.Tags[key]})
+		}
+		writeQueryResult(w, action, result)
+	case "GetRole":
+		role, ok := s.roles[get("RoleName")]
+		if !ok {
+			noSuchEntity(get("RoleName"))
+			return
+		}
+		writeQueryResult(w, action, struct{ Role iamRole }{encodeRole(get("RoleName"), role)})
+	case "CreateRole":
+		name := get("RoleName")
+		role := &Role{TrustPolicy: get("AssumeRolePolicyDocument"), Policies: map[string]string{}}
+		s.roles[name] = role
+		writeQueryResult(w, action, struct{ Role iamRole }{encodeRole(name, role)})
+	case "UpdateAssumeRolePolicy":
+		role, ok := s.roles[get("RoleName")]
+		if !ok {
+			noSuchEntity(get("RoleName"))
+			return
+		}
+		role.TrustPolicy = get("PolicyDocument")
+		writeQueryResult(w, action, struct{}{})
+	case "PutRolePolicy":
+		role, ok := s.roles[get("RoleName")]
+		if !ok {
+			noSuchEntity(get("RoleName"))
+			return
+		}
+		role.Policies[get("PolicyName")] = get("PolicyDocument")
+		writeQueryResult(w, action, struct{}{})
+	case "ListRolePolicies":
+		role, ok := s.roles[get("RoleName")]
+		if !ok {
+			noSuchEntity(get("RoleName"))
+			return
+		}
+		type policiesResult struct {
+			PolicyNames []string `xml:"PolicyNames>member"`
+			IsTruncated bool
+		}
+		writeQueryResult(w, action, policiesResult{PolicyNames: sortedKeys(role.Policies)})
+	case "DeleteRolePolicy":
+		if role, ok := s.roles[get("RoleName")]; ok {
+			delete(role.Policies, get("PolicyName"))
+		}
+		writeQueryResult(w, action, struct{}{})
+	case "DeleteRole":
+		if _, ok := s.roles[get("RoleName")]; !ok {
+			noSuchEntity(get("RoleName"))
+			return
+		}
+		delete(s.roles, get("RoleName"))
+		writeQueryResult(w, action, struct{}{})
+	case "AssumeRole":
+		name := get("RoleArn")[strings.LastIndex(get("RoleArn"), "/")+1:]
+		if _, ok := s.roles[name]; !ok {
+			writeQueryError(w, http.StatusForbidden, "AccessDenied", "cannot assume "+get("RoleArn"))
+			return
+		}
+		s.sessions++
+		duration := time.Hour
+		if seconds := get("DurationSeconds"); seconds != "" {
+			if parsed, err := time.ParseDuration(seconds + "s"); err == nil {
+				duration = parsed
+			}
+		}
+		type credentials struct {
+			AccessKeyId     string
+			SecretAccessKey string
+			SessionToken    string
+			Expiration      string
+		}
+		writeQueryResult(w, action, struct{ Credentials credentials }{credentials{
+			AccessKeyId:     fmt.Sprintf("ASIAFAKE%08d", s.sessions),
+			SecretAccessKey: fmt.Sprintf("fake-session-secret-%d", s.sessions),
+			SessionToken:    fmt.Sprintf("fake-session-token-%d", s.sessions),
+			Expiration:      time.Now().Add(duration).UTC().Format(time.RFC3339),
+		}})
+	default:
+		writeQueryError(w, http.StatusBadRequest, "InvalidAction", "unsupported action "+action)
+	}
+}
+
+func encodeRole(name string, role *Role) iamRole {
+	return iamRole{
+		RoleName:                 name,
+		RoleId:                   "AROA" + strings.ToUpper(name),
+		Arn:                      RoleARN(name),
+		Path:                     "/",
+		CreateDate:               time.Now().UTC().Format(time.RFC3339),
+		AssumeRolePolicyDocument: role.TrustPolicy,
+	}
+}
+
+func (s *Server) serveS3(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+	query := r.URL.Query()
+	_, policy := query["policy"]
+	_, tagging := query["tagging"]
+
+	operation := r.Method
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		operation = "ListBuckets"
+	case policy:
+		operation = map[string]string{http.MethodGet: "GetBucketPolicy", http.MethodPut: "PutBucketPolicy", http.MethodDelete: "DeleteBucketPolicy"}[r.Method]
+	case tagging:
+		operation = map[string]string{http.MethodGet: "GetBucketTagging", http.MethodPut: "PutBucketTagging", http.MethodDelete: "DeleteBucketTagging"}[r.Method]
+	default:
+		operation = map[string]string{http.MethodPut: "CreateBucket", http.MethodDelete: "DeleteBucket", http.MethodHead: "HeadBucket", http.MethodGet: "ListObjects"}[r.Method]
+	}
+	s.requests = append(s.requests, "s3:"+operation)
+
+	if operation == "ListBuckets" {
+		type bucket struct {
+			Name         string
+			CreationDate string
+		}
+		type listResult struct {
+			XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+			Buckets []bucket `xml:"Buckets>Bucket"`
+		}
+		result := listResult{}
+		for _, bucketName := range sortedKeys(s.buckets) {
+			result.Buckets = append(result.Buckets, bucket{bucketName, time.Now().UTC().Format(time.RFC3339)})
+		}
+		writeXML(w, http.StatusOK, result)
+		return
+	}
+
+	bucket, ok := s.buckets[name]
+	if operation == "CreateBucket" {
+		if s.taken[name] {
+			writeS3Error(w, http.StatusConflict, "BucketAlreadyExists", name)
+			return
+		}
+		if ok {
+			writeS3Error(w, http.StatusConflict, "BucketAlreadyOwnedByYou", name)
+			return
+		}
+		s.buckets[name] = &Bucket{Tags: map[string]string{}}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", name)
+		return
+	}
+
+	switch operation {
+	case "HeadBucket":
+		w.WriteHeader(http.StatusOK)
+	case "DeleteBucket":
+		delete(s.buckets, name)
+		w.WriteHeader(http.StatusNoContent)
+	case "ListObjects":
+		type listResult struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			IsTruncated bool
+		}
+		writeXML(w, http.StatusOK, listResult{Name: name})
+	case "GetBucketPolicy":
+		if bucket.Policy == "" {
+			writeS3Error(w, http.StatusNotFound, "NoSuchBucketPolicy", name)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, bucket.Policy)
+	case "PutBucketPolicy":
+		body, _ := io.ReadAll(r.Body)
+		bucket.Policy = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	case "DeleteBucketPolicy":
+		bucket.Policy = ""
+		w.WriteHeader(http.StatusNoContent)
+	case "GetBucketTagging":
+		if len(bucket.Tags) == 0 {
+			writeS3Error(w, http.StatusNotFound, "NoSuchTagSet", name)
+			return
+		}
+		type tag struct {
+			Key   string
+			Value string
+		}
+		type taggingResult struct {
+			XMLName xml.Name `xml:"Tagging"`
+			TagSet  []tag    `xml:"TagSet>Tag"`
+		}
+		result := taggingResult{}
+		for _, key := range sortedKeys(bucket.Tags) {
+			result.TagSet = append(result.TagSet, tag{key, bucket.Tags[key]})
+		}
+		writeXML(w, http.StatusOK, result)
+	case "PutBucketTagging":
+		var tagging struct {
+			TagSet []struct {
+				Key   string
+				Value string
+			} `xml:"TagSet>Tag"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &tagging); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		bucket.Tags = map[string]string{}
+		for _, tag := range tagging.TagSet {
+			bucket.Tags[tag.Key] = tag.Value
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "DeleteBucketTagging":
+		bucket.Tags = map[string]string{}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeQueryResult writes the response of an IAM or STS query API action
+func writeQueryResult(w http.ResponseWriter, action string, result interface{}) {
+	var body strings.Builder
+	encoder := xml.NewEncoder(&body)
+	if err := encoder.EncodeElement(result, xml.StartElement{Name: xml.Name{Local: action + "Result"}}); err != nil {
+		writeQueryError(w, http.StatusInternalServerError, "InternalFailure", err.Error())
+		return
+	}
+	if err := encoder.Flush(); err != nil {
+		writeQueryError(w, http.StatusInternalServerError, "InternalFailure", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = fmt.Fprintf(w, "<%[1]sResponse>%[2]s<ResponseMetadata><RequestId>fake</RequestId></ResponseMetadata></%[1]sResponse>",
+		action, body.String())
+}
+
+func writeQueryError(w http.ResponseWriter, code int, errorCode, message string) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintf(w, "<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error><RequestId>fake</RequestId></ErrorResponse>",
+		errorCode, xmlEscape(message))
+}
+
+func writeS3Error(w http.ResponseWriter, code int, errorCode, resource string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message><Resource>%s</Resource><RequestId>fake</RequestId></Error>",
+		errorCode, errorCode, xmlEscape(resource))
+}
+
+func writeXML(w http.ResponseWriter, code int, v interface{}) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		writeS3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,67 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/logging"
+)
+
+// STSClientInterface is an interface for STS operations
+type STSClientInterface interface {
+	AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
+}
+
+// STSClient wraps the STS API
+type STSClient struct {
+	api *sts.STS
+}
+
+// NewSTSClient creates a new STS client
+func NewSTSClient(params *S3ClientParams, debug bool) (*STSClient, error) {
+	logLevel := aws.LogOff
+	if debug {
+		logLevel = aws.LogDebug
+	}
+
+	endpoint := params.GetFullSTSEndpoint()
+	klog.V(5).InfoS("Creating STS client with endpoint", "endpoint", endpoint)
+	client, err := newHTTPClient(params, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	stsSession, err := session.NewSession(
+		aws.NewConfig().
+			WithRegion(params.Region).
+			WithCredentials(credentials.NewStaticCredentials(params.AccessKey, params.SecretKey, "")).
+			WithEndpoint(endpoint).
+			WithMaxRetries(5).
+			WithDisableSSL(!isSecureEndpoint(endpoint)).
+			WithHTTPClient(client).
+			WithLogLevel(logLevel).
+			WithLogger(logging.AWSLogger()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	instrumentSession(stsSession, serviceSTS)
+
+	return &STSClient{
+		api: sts.New(stsSession),
+	}, nil
+}
+
+// AssumeRole requests temporary credentials for a role
+func (c *STSClient) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	return c.api.AssumeRole(input)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"k8s.io/klog/v2"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/audit"
+)
+
+// sortedTagKeys returns the keys of tags in a stable order, so that requests are reproducible
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TagUser adds tags to an IAM user, replacing the values of existing keys
+func (a *IAMClient) TagUser(userName string, tags map[string]string) error {
+	input := &iam.TagUserInput{UserName: aws.String(userName)}
+	for _, key := range sortedTagKeys(tags) {
+		input.Tags = append(input.Tags, &iam.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	_, err := a.api.TagUser(input)
+	return err
+}
+
+// ListUserTags returns the tags of an IAM user
+func (a *IAMClient) ListUserTags(userName string) (map[string]string, error) {
+	tags := map[string]string{}
+	err := a.api.ListUserTagsPages(&iam.ListUserTagsInput{UserName: aws.String(userName)},
+		func(page *iam.ListUserTagsOutput, _ bool) bool {
+			for _, tag := range page.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// TagUser tags the IAM user, recording the mutation in the audit log
+func (s *S3Client) TagUser(ctx context.Context, userName string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	klog.InfoS("Tagging IAM user", "userName", userName, "tags", tags)
+	err := s.IAM.TagUser(userName, tags)
+	s.recordAudit(ctx, audit.Event{Action: audit.ActionTagUser, User: userName}, err)
+	return err
+}
+
+// GetBucketTagging returns the tags of the bucket, which are empty when it has none
+func (s *S3Client) GetBucketTagging(bucketName string) (map[string]string, error) {
+	output, err := s.S3.GetBucketTagging(&s3.GetBucketTaggingInput{Bucket: aws.String(bucketName)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchTagSet" {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// PutBucketTagging adds tags to the bucket, replacing the values of existing keys, recording the
+// mutation in the audit log. S3 replaces the whole tag set, so the tags set by others, e.g. for
+// cost allocation, are read first and kept.
+func (s *S3Client) PutBucketTagging(ctx context.Context, bucketName string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	merged, err := s.GetBucketTagging(bucketName)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for key, value := range tags {
+		if current, ok := merged[key]; !ok || current != value {
+			merged[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		klog.V(3).InfoS("Bucket already has its tags", "bucketName", bucketName)
+		return nil
+	}
+
+	input := &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Tagging: &s3.Tagging{},
+	}
+	for _, key := range sortedTagKeys(merged) {
+		input.Tagging.TagSet = append(input.Tagging.TagSet, &s3.Tag{Key: aws.String(key), Value: aws.String(merged[key])})
+	}
+	klog.InfoS("Tagging bucket", "bucketName", bucketName, "tags", tags)
+	_, err = s.S3.PutBucketTagging(input)
+	s.recordAudit(ctx, audit.Event{Action: audit.ActionPutBucketTagging, Bucket: bucketName}, err)
+	return err
+}
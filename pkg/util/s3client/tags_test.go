@@ -0,0 +1,68 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.ibm.com/graphene/s3-iam-cosi-driver/pkg/util/s3client/s3clienttest"
+)
+
+func TestPutBucketTaggingKeepsExistingTags(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	server.AddBucket("bucket1", "")
+	client := newTestClient(t, server, ProviderMinIO)
+
+	// Tags set on the backend by someone else
+	_, err := client.S3.PutBucketTagging(&s3.PutBucketTaggingInput{
+		Bucket: aws.String("bucket1"),
+		Tagging: &s3.Tagging{TagSet: []*s3.Tag{
+			{Key: aws.String("cost-center"), Value: aws.String("ds-42")},
+			{Key: aws.String("s3-iam.objectstorage.k8s.io/namespace"), Value: aws.String("old")},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := map[string]string{"s3-iam.objectstorage.k8s.io/namespace": "team-a", "s3-iam.objectstorage.k8s.io/bucket-claim": "data"}
+	if err := client.PutBucketTagging(context.Background(), "bucket1", tags); err != nil {
+		t.Fatalf("PutBucketTagging: %v", err)
+	}
+	bucket, _ := server.Bucket("bucket1")
+	want := map[string]string{"cost-center": "ds-42", "s3-iam.objectstorage.k8s.io/namespace": "team-a", "s3-iam.objectstorage.k8s.io/bucket-claim": "data"}
+	if !reflect.DeepEqual(bucket.Tags, want) {
+		t.Errorf("tags = %v, want %v", bucket.Tags, want)
+	}
+
+	// Tagging again with the same tags leaves the bucket alone
+	puts := server.Count("s3:PutBucketTagging")
+	if err := client.PutBucketTagging(context.Background(), "bucket1", tags); err != nil {
+		t.Fatalf("PutBucketTagging: %v", err)
+	}
+	if server.Count("s3:PutBucketTagging") != puts {
+		t.Error("unchanged tags were written again")
+	}
+}
+
+func TestPutBucketTaggingWithoutTagSet(t *testing.T) {
+	server := s3clienttest.NewServer(t)
+	server.AddBucket("bucket1", "")
+	client := newTestClient(t, server, ProviderMinIO)
+
+	if err := client.PutBucketTagging(context.Background(), "bucket1", map[string]string{"s3-iam.objectstorage.k8s.io/bucket-claim": "data"}); err != nil {
+		t.Fatalf("PutBucketTagging: %v", err)
+	}
+	if bucket, _ := server.Bucket("bucket1"); bucket.Tags["s3-iam.objectstorage.k8s.io/bucket-claim"] != "data" {
+		t.Errorf("tags = %v", bucket.Tags)
+	}
+}
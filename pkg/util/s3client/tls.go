@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2024-2025 IBM Corporation
+
+Licensed under the MIT License.
+*/
+
+package s3client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// isSecureEndpoint reports whether endpoint is reached over TLS
+func isSecureEndpoint(endpoint string) bool {
+	return !strings.HasPrefix(endpoint, "http://")
+}
+
+// newHTTPClient creates the HTTP client used to reach endpoint, configured with the TLS
+// options of the account Secret when the endpoint uses https
+func newHTTPClient(params *S3ClientParams, endpoint string) (*http.Client, error) {
+	client := &http.Client{
+		Timeout: HttpTimeOut,
+	}
+	if !isSecureEndpoint(endpoint) {
+		klog.V(5).InfoS("Using insecure endpoint", "endpoint", endpoint)
+		return client, nil
+	}
+
+	transport, err := buildTransportTLS(params)
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = transport
+	return client, nil
+}
+
+// buildTransportTLS creates a transport trusting the system roots plus the CA bundle of the account
+// Secret, presenting its client certificate if one is configured
+func buildTransportTLS(params *S3ClientParams) (*http.Transport, error) {
+	//nolint:gosec // InsecureSkipVerify is an explicit opt-in of the account Secret
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: params.InsecureSkipVerify,
+	}
+
+	if len(params.TlsCert) > 0 {
+		klog.V(5).InfoS("Using TLS certificate")
+		caCertPool, err := x509.SystemCertPool()
+		if err != nil {
+			klog.V(3).InfoS("System certificate pool unavailable, trusting only the provided CA", "error", err)
+			caCertPool = x509.NewCertPool()
+		}
+		if !caCertPool.AppendCertsFromPEM(params.TlsCert) {
+			return nil, fmt.Errorf("failed to parse TlsCert: no PEM certificates found")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	if len(params.TlsClientCert) > 0 {
+		klog.V(5).InfoS("Using TLS client certificate")
+		clientCert, err := tls.X509KeyPair(params.TlsClientCert, params.TlsClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}